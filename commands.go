@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/aybabtme/gostatic/gen"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// runList prints the files embedded in a generated package.
+func runList(ctx context.Context, args []string) {
+	fs := newFlagSet("list", "[flags] pkgdir", "Print the files embedded in a generated package, or in the file written with -out.")
+	long := fs.Bool("l", false, "also print the mode, size and modification time of the files")
+	parseFlags(fs, args)
+	assets := loadAssets(fs, 1)
+
+	if !*long {
+		for _, a := range assets {
+			fmt.Println(a.Name)
+		}
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, a := range assets {
+		modTime := "-"
+		if !a.ModTime.IsZero() {
+			modTime = a.ModTime.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%v\t%d\t%s\t%s\t%s\n", a.Mode, a.Size, modTime, a.Root, a.Name)
+	}
+	_ = w.Flush()
+}
+
+// runExtract writes the files embedded in a generated package back to disk.
+func runExtract(ctx context.Context, args []string) {
+	fs := newFlagSet("extract", "[flags] pkgdir [outdir]", "Write the files embedded in a generated package back to disk, to outdir if given.")
+	dir := fs.String("o", ".", "directory to write the files to, like outdir")
+	parseFlags(fs, args)
+	if fs.NArg() == 2 {
+		*dir = fs.Arg(1)
+	}
+	assets := loadAssets(fs, 2)
+
+	for _, a := range assets {
+		name := path.Clean(a.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			elog.Fatalf("Refusing to extract %q outside of %q", a.Name, *dir)
+		}
+		data, err := a.Data()
+		if err != nil {
+			elog.Fatal(err)
+		}
+		filename := filepath.Join(*dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			elog.Fatal(err)
+		}
+		mode := a.Mode.Perm()
+		if mode == 0 {
+			// the package was generated before the modes were recorded
+			mode = 0644
+		}
+		if err := ioutil.WriteFile(filename, data, mode); err != nil {
+			elog.Fatal(err)
+		}
+		// the file may exist already, or the umask may have taken bits away
+		if err := os.Chmod(filename, mode); err != nil {
+			elog.Fatal(err)
+		}
+		if !a.ModTime.IsZero() {
+			if err := os.Chtimes(filename, a.ModTime, a.ModTime); err != nil {
+				elog.Fatal(err)
+			}
+		}
+	}
+	dirs, err := gen.LoadEmptyDirs(fs.Arg(0))
+	if err != nil {
+		elog.Fatal(err)
+	}
+	for _, d := range dirs {
+		name := path.Clean(d)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			elog.Fatalf("Refusing to extract %q outside of %q", d, *dir)
+		}
+		if err := os.MkdirAll(filepath.Join(*dir, filepath.FromSlash(name)), 0755); err != nil {
+			elog.Fatal(err)
+		}
+	}
+	if len(dirs) != 0 {
+		log.Printf("Extracted %d files and %d empty directories to %q", len(assets), len(dirs), *dir)
+		return
+	}
+	log.Printf("Extracted %d files to %q", len(assets), *dir)
+}
+
+// runDiff prints the differences between the directories and a generated
+// package.
+func runDiff(ctx context.Context, args []string) {
+	fs := newFlagSet("diff", "[flags] [pkgdir] dirnames or filenames", "Print the files that changed since the package was generated, with the flags it was generated with.")
+	options := genFlags(fs)
+	parseFlags(fs, args)
+	pkgdir := packageArg(fs)
+	for _, opts := range withPackage(options(), pkgdir) {
+		changes, err := gen.Diff(ctx, opts)
+		if err != nil {
+			elog.Fatalf("Couldn't compare package %q: %v", packageLabel(opts), err)
+		}
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+}
+
+// runVerify exits with an error if a generated package doesn't hold the
+// content of the directories, or if its data is damaged. Like diff(1), it
+// exits with 1 if they differ, and 2 if they can't be compared.
+func runVerify(ctx context.Context, args []string) {
+	fs := newFlagSet("verify", "[flags] [pkgdir] dirnames or filenames", "Verify that a generated package holds the content of the directories and files, with the flags it was generated with.")
+	options := genFlags(fs)
+	parseFlags(fs, args)
+	pkgdir := packageArg(fs)
+	differ := false
+	for _, opts := range withPackage(options(), pkgdir) {
+		pkg := packageLabel(opts)
+		if pkgdir != "" {
+			pkg = pkgdir
+		}
+		changes, err := gen.Verify(ctx, opts)
+		if err != nil {
+			elog.Printf("Couldn't verify package %q: %v", pkg, err)
+			os.Exit(2)
+		}
+		for _, c := range changes {
+			elog.Print(c)
+		}
+		if len(changes) != 0 {
+			elog.Printf("Package %q doesn't match the directories", pkg)
+			differ = true
+			continue
+		}
+		log.Printf("Package %q matches the directories", pkg)
+	}
+	if differ {
+		os.Exit(1)
+	}
+}
+
+// packageArg takes the generated package given as the first argument of fs,
+// before the directories, out of the arguments, and returns it. It returns ""
+// if the first argument isn't a generated package, which is found with the
+// flags then.
+func packageArg(fs *flag.FlagSet) string {
+	if fs.NArg() < 2 || !gen.IsPackage(fs.Arg(0)) {
+		return ""
+	}
+	pkgdir := fs.Arg(0)
+	_ = fs.Parse(fs.Args()[1:])
+	return pkgdir
+}
+
+// withPackage has opts compare the package found at pkgdir, if set, a
+// directory or the single file written with -out. There can't be several
+// packages then.
+func withPackage(pkgs []gen.Options, pkgdir string) []gen.Options {
+	if pkgdir == "" {
+		return pkgs
+	}
+	if len(pkgs) > 1 {
+		elog.Fatalf("Invalid package %q along with -spec, which gives the packages", pkgdir)
+	}
+	opts := &pkgs[0]
+	if fi, err := os.Stat(pkgdir); err == nil && fi.IsDir() {
+		opts.Output, opts.Out = pkgdir, ""
+	} else {
+		opts.Out = pkgdir
+	}
+	return pkgs
+}
+
+// loadAssets reads the assets of the package named by the first argument of
+// fs, which takes up to maxArgs.
+func loadAssets(fs *flag.FlagSet, maxArgs int) []gen.Asset {
+	if fs.NArg() < 1 || fs.NArg() > maxArgs {
+		fs.Usage()
+		os.Exit(2)
+	}
+	assets, err := gen.Load(fs.Arg(0))
+	if err != nil {
+		elog.Fatalf("Couldn't load package: %v", err)
+	}
+	return assets
+}
+
+// runMigrate replaces a package generated by go-bindata, statik or packr with
+// one generated by gostatic.
+func runMigrate(ctx context.Context, args []string) {
+	fs := newFlagSet("migrate", "[flags] path", "Replace the package generated by go-bindata, statik or packr found at path, a directory or a file, with one generated by gostatic that keeps its API.")
+	var opts gen.Options
+	fs.StringVar(&opts.PkgName, "pkgname", "", "name of the package to create, the one of the package replaced by default")
+	fs.StringVar(&opts.Output, "o", "", "directory to write the package to, the one of the package replaced by default")
+	fs.StringVar(&opts.Name, "name", "assets", "name of the file and functions of the package")
+	dir := fs.String("d", "", "directory to write the embedded files to, named after -name in the package by default")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	opts.Log = infoLog()
+	opts.Verbose = verbose
+	opts.ErrorLog = elog
+
+	legacy, err := gen.LoadLegacy(fs.Arg(0))
+	if err != nil {
+		elog.Fatalf("Couldn't load package: %v", err)
+	}
+	log.Printf("Found %d files embedded by %s", len(legacy.Files), legacy.Tool)
+	if err := gen.Migrate(ctx, legacy, *dir, opts); err != nil {
+		elog.Fatalf("Couldn't migrate package: %v", err)
+	}
+	if legacy.Tool == gen.Packr {
+		log.Printf("Import the new package for its side effects where packr boxes are used")
+	}
+}
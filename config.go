@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadConfig sets the flags of fs found in the config file called filename,
+// in TOML if it ends with .toml and in YAML otherwise, and returns the
+// directories it lists under dirs. The keys are the names of the flags, and
+// the flags given on the command line are left as they are. A list sets a
+// repeated flag once per element:
+//
+//	pkgname: staticfs
+//	o: internal/staticfs
+//	dirs: [web/dist, templates]
+//	exclude: ["**/*.map"]
+//	map: {web/dist: Web}
+func loadConfig(fs *flag.FlagSet, filename string) ([]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]interface{})
+	if strings.EqualFold(filepath.Ext(filename), ".toml") {
+		err = toml.Unmarshal(data, &config)
+	} else {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", filename, err)
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var dirs []string
+	for _, key := range keys {
+		if key == "dirs" {
+			if dirs, err = configList(config[key]); err != nil {
+				return nil, fmt.Errorf("dirs: %v", err)
+			}
+			continue
+		}
+		if key == "config" || fs.Lookup(key) == nil {
+			return nil, fmt.Errorf("unknown flag %q", key)
+		}
+		if given[key] {
+			continue
+		}
+		var values []string
+		if _, repeated := fs.Lookup(key).Value.(*stringList); repeated {
+			switch v := config[key].(type) {
+			case map[string]interface{}:
+				// like the -spec of each package
+				values, err = configValues(v)
+			case []interface{}:
+				values, err = configList(v)
+			default:
+				values, err = configList([]interface{}{v})
+			}
+		} else {
+			values, err = configValues(config[key])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		for _, value := range values {
+			if err := fs.Set(key, value); err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+		}
+	}
+	return dirs, nil
+}
+
+// configValues returns the values to set a flag to, from the value found in
+// a config file. A list is a comma separated value, and a mapping is a value
+// written key=value for each of its keys, where value can be a list too.
+func configValues(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		list, err := configList(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{strings.Join(list, ",")}, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]string, 0, len(v))
+		for _, key := range keys {
+			var value string
+			var err error
+			if list, ok := v[key].([]interface{}); ok {
+				var elems []string
+				elems, err = configList(list)
+				value = strings.Join(elems, ",")
+			} else {
+				value, err = configScalar(v[key])
+			}
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, key+"="+value)
+		}
+		return values, nil
+	}
+	value, err := configScalar(v)
+	if err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
+
+// configList returns the elements of a list found in a config file.
+func configList(v interface{}) ([]string, error) {
+	elems, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("want a list")
+	}
+	list := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		value, err := configScalar(elem)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+// configScalar returns a string, a number or a boolean found in a config
+// file as a flag value.
+func configScalar(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("want a string, a number or a boolean, not %T", v)
+}
@@ -0,0 +1,199 @@
+package gen
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// archiveExts are the extensions of the archives embedded like directories.
+var archiveExts = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// IsArchive tells if the file called name is a zip or tar archive, possibly
+// gzip compressed, from its extension. Archives are embedded like the
+// directories they hold.
+func IsArchive(name string) bool {
+	return archiveExt(name) != ""
+}
+
+// archiveExt returns the extension of the archive called name, or "" if it
+// isn't one.
+func archiveExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lower, ext) && len(name) > len(ext) {
+			return name[len(name)-len(ext):]
+		}
+	}
+	return ""
+}
+
+// isArchive tells if dirname, given as a directory, is an archive instead.
+func isArchive(dirname string) bool {
+	if !IsArchive(dirname) {
+		return false
+	}
+	fi, err := os.Stat(dirname)
+	return err == nil && !fi.IsDir()
+}
+
+// archiveDir returns the directory the archive called name stands for, which
+// the files it holds are named after, its name without extension.
+func archiveDir(name string) string {
+	return name[:len(name)-len(archiveExt(name))]
+}
+
+// archived is a file found in an archive, at the slash separated path name.
+type archived struct {
+	name string
+	fi   os.FileInfo
+	read func() ([]byte, error)
+}
+
+// openArchive lists the files of the archive called filename, in the order
+// they are found. The files of a zip archive are read when they are needed,
+// until close is called, while those of a tar archive are read right away.
+func openArchive(filename string) (files []archived, close func() error, err error) {
+	if strings.EqualFold(archiveExt(filename), ".zip") {
+		zr, err := zip.OpenReader(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range zr.File {
+			f := f
+			files = append(files, archived{name: f.Name, fi: f.FileInfo(), read: func() ([]byte, error) {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer func() { _ = rc.Close() }()
+				return ioutil.ReadAll(rc)
+			}})
+		}
+		return files, zr.Close, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = file.Close() }()
+	var r io.Reader = file
+	if ext := strings.ToLower(archiveExt(filename)); ext == ".tar.gz" || ext == ".tgz" {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = zr
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		f := archived{name: hdr.Name, fi: hdr.FileInfo()}
+		if hdr.Typeflag == tar.TypeReg {
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("couldn't read %q: %v", hdr.Name, err)
+			}
+			f.read = func() ([]byte, error) { return data, nil }
+		}
+		files = append(files, f)
+	}
+	return files, func() error { return nil }, nil
+}
+
+// snapshotArchive returns the entries of the files of the archive called
+// filename to embed, sorted by name, like snapshotDir does for the directory
+// as, which they are named after. The ignore files don't apply to archives,
+// and the symlinks they hold are skipped.
+func (g *generator) snapshotArchive(ctx context.Context, filename, as string) ([]entry, error) {
+	archive, close, err := openArchive(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = close() }()
+
+	var files []input
+	renamed := make(map[string]string)
+	// the directories are empty if nothing is found in them
+	var dirs []string
+	holders := make(map[string]bool)
+	for _, f := range archive {
+		if err := ctx.Err(); err != nil {
+			return nil, &Interrupted{File: filename, Err: err}
+		}
+		// the names are relative to the archive, whatever they start with
+		rel := strings.TrimPrefix(path.Clean("/"+f.name), "/")
+		if rel == "" {
+			continue
+		}
+		for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+			holders[dir] = true
+		}
+		if f.fi.IsDir() {
+			if g.EmptyDirs && !g.excluded(rel) {
+				dirs = append(dirs, rel)
+			}
+			continue
+		}
+		name := filename + "/" + rel
+		if g.excluded(rel) {
+			g.filter(name, false, "excluded")
+			continue
+		}
+		if f.fi.Mode()&os.ModeSymlink != 0 {
+			if g.Symlinks == "error" {
+				return nil, fmt.Errorf("%q is a symlink, skip symlinks", name)
+			}
+			g.logf("Skipped symlink %q", name)
+			g.report.filter(name, false, "symlink")
+			continue
+		}
+		if f.read == nil || !f.fi.Mode().IsRegular() {
+			g.filter(name, false, "not a regular file")
+			continue
+		}
+		if len(g.include) != 0 && !g.include.match(rel) {
+			g.filter(name, false, "not included")
+			continue
+		}
+		if err := g.budget(name, f.fi.Size()); err != nil {
+			return nil, err
+		}
+		in, err := g.input(name, path.Join(namePrefix(as), rel), f.fi, renamed)
+		if err != nil {
+			return nil, err
+		}
+		in.read = f.read
+		files = append(files, in)
+	}
+	for _, rel := range dirs {
+		if !holders[rel] {
+			g.emptyDir(path.Join(namePrefix(as), rel))
+		}
+	}
+	return g.read(ctx, filename, files)
+}
+
+// excluded tells if the file found at rel in an archive, or one of the
+// directories holding it, is excluded.
+func (g *generator) excluded(rel string) bool {
+	for ; rel != "."; rel = path.Dir(rel) {
+		if g.exclude.match(rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressBrotli compresses data with Brotli at its best level, which is slow
+// but only done once. It returns nil if the result isn't smaller than size,
+// the size of the data stored for the file already.
+func compressBrotli(data []byte, size int) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	bw := brotli.NewWriterLevel(buf, brotli.BestCompression)
+	if _, err := bw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() >= size {
+		return nil, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// addBrotli stores br as the Brotli variant of the entry of the file called
+// name, if there is one.
+func (g *generator) addBrotli(e *entry, name string, br []byte) error {
+	if br == nil {
+		return nil
+	}
+	literal, chunked := g.literal(br)
+	p := payload{length: int64(len(literal))}
+	if !g.dryRun {
+		var err error
+		if p, err = g.spool.add(literal); err != nil {
+			return fmt.Errorf("couldn't spool %q: %v", name, err)
+		}
+	}
+	e.Brotli, e.BrotliChunked = true, chunked
+	e.brotli, e.brotliSize = p, len(br)
+	return nil
+}
+
+// BrotliLiteral returns the encoded Brotli variant of the file, read back
+// from the spool, or the slice of the pack holding it.
+func (e entry) BrotliLiteral() (string, error) {
+	if e.brotliPacked != "" {
+		return e.brotliPacked, nil
+	}
+	return e.brotli.literal()
+}
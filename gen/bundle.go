@@ -0,0 +1,146 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// packExt is the extension of the files holding the stored content of the
+// files of a root, with the bundle backend, and pakExt with the pak backend.
+const (
+	packExt = ".pack"
+	pakExt  = ".pak"
+)
+
+// pack is the file holding the stored content of the files of a root one
+// after the other. With the bundle backend, it is embedded in a string
+// called Var, which the assets slice. With the pak backend, it is read at
+// run time, and Var is the pak the assets read from, expecting the file to
+// be Size bytes long, with the hex encoded SHA-256 Sum of its content.
+type pack struct {
+	Var  string
+	File string
+	Size int64
+	Sum  string
+}
+
+// span is where stored content is found in a pack.
+type span struct {
+	Var            string
+	Offset, Length int64
+}
+
+// packer lays payloads out one after the other, after the header of their
+// pack.
+type packer struct {
+	v        string
+	offset   int64
+	payloads []payload
+}
+
+func (p *packer) add(pl payload) span {
+	s := span{Var: p.v, Offset: p.offset, Length: pl.length}
+	p.payloads = append(p.payloads, pl)
+	p.offset += pl.length
+	return s
+}
+
+// copy writes the payloads to w.
+func (p *packer) copy(w io.Writer) error {
+	for _, pl := range p.payloads {
+		if _, err := io.Copy(w, io.NewSectionReader(pl.spool.file, pl.offset, pl.length)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bundle lays the stored content of the entries and blocks out in the pack
+// of the root called name, has them slice it, and adds the pack to out. The
+// entries with the same content as others must be found already.
+func (g *generator) bundle(out generated, name string, entries []entry, blocks []block) *pack {
+	p := &pack{Var: "pack" + camelize(name), File: snakify(name) + packExt}
+	// the header tells the pack was generated, the content follows it
+	pk := &packer{v: p.Var, offset: int64(len(generatedHeader) + 1)}
+	slice := func(pl payload) string {
+		s := pk.add(pl)
+		return fmt.Sprintf("%s[%d:%d]", s.Var, s.Offset, s.Offset+s.Length)
+	}
+	for i := range entries {
+		e := &entries[i]
+		if e.Dup != "" {
+			continue
+		}
+		if e.Block == "" {
+			e.packed = slice(e.payload)
+		}
+		if e.Brotli {
+			e.brotliPacked = slice(e.brotli)
+		}
+	}
+	for i := range blocks {
+		blocks[i].packed = slice(blocks[i].payload)
+	}
+	out[p.File] = func(w io.Writer) error {
+		if _, err := io.WriteString(w, generatedHeader+"\n"); err != nil {
+			return err
+		}
+		return pk.copy(w)
+	}
+	return p
+}
+
+// pakHeader starts the pak files, followed by the hex encoded SHA-256 of
+// their content, on its own line, for the generated code to check it reads
+// the right file.
+const pakHeader = generatedHeader + "\n"
+
+// pak lays the stored content of the entries and blocks out in the pak of the
+// root called name, read at run time, has them point at it, and adds the pak
+// to out. The content kept in Go literals is left out, and there is no pak
+// if all of it is. The entries with the same content as others must be found
+// already.
+func (g *generator) pak(out generated, name string, entries []entry, blocks []block) (*pack, error) {
+	p := &pack{Var: "pak" + camelize(name), File: snakify(name) + pakExt}
+	pk := &packer{v: p.Var, offset: int64(len(pakHeader) + sha256.Size*2 + 1)}
+	for i := range entries {
+		e := &entries[i]
+		if e.Dup != "" {
+			continue
+		}
+		if e.Block == "" && g.external(e.stored) {
+			s := pk.add(e.payload)
+			e.Pak = &s
+		}
+		if e.Brotli && g.external(e.brotliSize) {
+			s := pk.add(e.brotli)
+			e.BrotliPak = &s
+		}
+	}
+	for i := range blocks {
+		if g.external(blocks[i].stored) {
+			s := pk.add(blocks[i].payload)
+			blocks[i].Pak = &s
+		}
+	}
+	if len(pk.payloads) == 0 {
+		return nil, nil
+	}
+	p.Size = pk.offset
+	if !g.dryRun {
+		h := sha256.New()
+		if err := pk.copy(h); err != nil {
+			return nil, fmt.Errorf("couldn't hash %s: %v", p.File, err)
+		}
+		p.Sum = hex.EncodeToString(h.Sum(nil))
+	}
+	out[p.File] = func(w io.Writer) error {
+		if _, err := io.WriteString(w, pakHeader+p.Sum+"\n"); err != nil {
+			return err
+		}
+		return pk.copy(w)
+	}
+	return p, nil
+}
@@ -0,0 +1,112 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// cacheVersion changes whenever what the cache holds does, so that older
+// caches are ignored.
+const cacheVersion = "gostatic-cache-1"
+
+// cache keeps what was stored for the files across runs, keyed by the hash of
+// their content and of the options changing what is stored, so that the
+// files that didn't change aren't minified and compressed again.
+type cache struct {
+	filename string
+
+	mu sync.Mutex
+	// old is what the cache held, and used what this run needed, which is
+	// all that is saved
+	old  map[string]cached
+	used map[string]cached
+}
+
+// cached is what was stored for a file.
+type cached struct {
+	Stored     []byte
+	Compressed bool
+	// Brotli is the Brotli variant, if any
+	Brotli []byte
+}
+
+// loadCache reads the cache kept in the file called filename, which is empty
+// if it doesn't exist yet.
+func loadCache(filename string) (*cache, error) {
+	c := &cache{
+		filename: filename,
+		old:      make(map[string]cached),
+		used:     make(map[string]cached),
+	}
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	var version string
+	dec := gob.NewDecoder(file)
+	if err := dec.Decode(&version); err != nil || version != cacheVersion {
+		// written by another version, start over
+		return c, nil
+	}
+	if err := dec.Decode(&c.old); err != nil {
+		return nil, fmt.Errorf("couldn't read cache %s: %v", filename, err)
+	}
+	return c, nil
+}
+
+// key returns the key of a file holding data, to be stored with the settings.
+func (c *cache) key(data []byte, settings string) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintln(h, settings)
+	_, _ = h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *cache) get(key string) (cached, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.old[key]
+	if ok {
+		c.used[key] = v
+	}
+	return v, ok
+}
+
+func (c *cache) put(key string, v cached) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used[key] = v
+}
+
+// save writes what this run used to the file of the cache, replacing it at
+// once.
+func (c *cache) save() error {
+	file, err := ioutil.TempFile(filepath.Dir(c.filename), "."+filepath.Base(c.filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	enc := gob.NewEncoder(file)
+	err = enc.Encode(cacheVersion)
+	if err == nil {
+		err = enc.Encode(c.used)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.filename)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+	return err
+}
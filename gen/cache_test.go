@@ -0,0 +1,65 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestCache checks that the cache doesn't change the generated code, follows
+// the changes of the files, and only keeps what the last run used.
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "assets")
+	files := map[string][]byte{
+		"a.txt": bytes.Repeat([]byte("a"), 1000),
+		"b.txt": bytes.Repeat([]byte("b"), 1000),
+	}
+	writeFiles(t, dir, files)
+	cache := filepath.Join(t.TempDir(), "assets.gc")
+
+	read := func(out string) []byte {
+		t.Helper()
+		code, err := ioutil.ReadFile(filepath.Join(out, "assets.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	run := func(cache string) []byte {
+		t.Helper()
+		out := filepath.Join(t.TempDir(), "staticfs")
+		generate(t, Options{Dirs: []string{dir}, TrimPrefix: dir, Output: out, Cache: cache})
+		return read(out)
+	}
+
+	uncached := run("")
+	if first := run(cache); !bytes.Equal(first, uncached) {
+		t.Errorf("filling the cache changes the code: %s", summarizeDiff(uncached, first))
+	}
+	if second := run(cache); !bytes.Equal(second, uncached) {
+		t.Errorf("reading the cache changes the code: %s", summarizeDiff(uncached, second))
+	}
+
+	files["b.txt"] = bytes.Repeat([]byte("c"), 1000)
+	writeFiles(t, dir, files)
+	out := filepath.Join(t.TempDir(), "staticfs")
+	generate(t, Options{Dirs: []string{dir}, TrimPrefix: dir, Output: out, Cache: cache})
+	assets, err := Load(out)
+	if err != nil {
+		t.Fatalf("couldn't load the package: %v", err)
+	}
+	for _, a := range assets {
+		if data, err := a.Data(); err != nil || !bytes.Equal(data, files[a.Name]) {
+			t.Errorf("%q doesn't hold its new content: %v", a.Name, err)
+		}
+	}
+
+	c, err := loadCache(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.old) != len(files) {
+		t.Errorf("the cache holds %d files, want the %d of the last run", len(c.old), len(files))
+	}
+}
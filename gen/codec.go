@@ -0,0 +1,134 @@
+package gen
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// codec compresses the files at generation time. The generated code
+// decompresses them with the package of the same name.
+type codec struct {
+	Name string
+	// Import is the import path of the package used to decompress.
+	Import string
+
+	newWriter func(w io.Writer, level int) (io.WriteCloser, error)
+	newReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var codecs = map[string]codec{
+	"gzip": {
+		Name:   "gzip",
+		Import: "compress/gzip",
+		newWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			gw, err := gzip.NewWriterLevel(w, level)
+			if err != nil {
+				return nil, err
+			}
+			// no name nor modification time, so that the output only
+			// depends on the input
+			gw.Header = gzip.Header{OS: 255}
+			return gw, nil
+		},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	"zlib": {
+		Name:   "zlib",
+		Import: "compress/zlib",
+		newWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			return zlib.NewWriterLevel(w, level)
+		},
+		newReader: zlib.NewReader,
+	},
+	"flate": {
+		Name:   "flate",
+		Import: "compress/flate",
+		newWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			return flate.NewWriter(w, level)
+		},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			return flate.NewReader(r), nil
+		},
+	},
+	"zstd": {
+		Name:   "zstd",
+		Import: "github.com/klauspost/compress/zstd",
+		newWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+			return zstd.NewWriter(w,
+				zstd.WithEncoderLevel(zstdLevel(level)),
+				zstd.WithEncoderConcurrency(1),
+			)
+		},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return zr.IOReadCloser(), nil
+		},
+	},
+	"none": {
+		Name: "none",
+	},
+}
+
+// External tells if the package used to decompress is outside of the
+// standard library, in which case the generated package depends on it.
+func (c codec) External() bool {
+	return strings.Contains(strings.SplitN(c.Import, "/", 2)[0], ".")
+}
+
+// decompress reverses the compression of data.
+func (c codec) decompress(data []byte) ([]byte, error) {
+	if c.newReader == nil {
+		return data, nil
+	}
+	r, err := c.newReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return ioutil.ReadAll(r)
+}
+
+// compresses tells if the codec compresses anything at all.
+func (c codec) compresses() bool { return c.newWriter != nil }
+
+func lookupCodec(name string) (codec, error) {
+	c, ok := codecs[strings.ToLower(name)]
+	if !ok {
+		var names []string
+		for name := range codecs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return codec{}, fmt.Errorf("unknown codec %q, want one of %s", name, strings.Join(names, ", "))
+	}
+	return c, nil
+}
+
+// zstdLevel maps a flate level to the closest zstd encoder level.
+func zstdLevel(level int) zstd.EncoderLevel {
+	switch {
+	case level == flate.DefaultCompression:
+		return zstd.SpeedDefault
+	case level <= flate.BestSpeed:
+		return zstd.SpeedFastest
+	case level == flate.BestCompression:
+		return zstd.SpeedBestCompression
+	case level >= 6:
+		return zstd.SpeedBetterCompression
+	}
+	return zstd.SpeedDefault
+}
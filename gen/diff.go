@@ -0,0 +1,118 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// ChangeKind tells how a file differs from its asset.
+type ChangeKind string
+
+// The kinds of changes between the files of the directories and the assets
+// of a generated package.
+const (
+	// Added files aren't embedded yet.
+	Added ChangeKind = "added"
+	// Removed assets have no file anymore.
+	Removed ChangeKind = "removed"
+	// Modified files have another content than their asset.
+	Modified ChangeKind = "modified"
+	// Corrupted assets have another content than the one recorded when
+	// they were embedded, or can't be decoded.
+	Corrupted ChangeKind = "corrupted"
+)
+
+// Change is a difference between a file of the directories and its asset in
+// a generated package.
+type Change struct {
+	// Root names the accessors of the asset, like Static for GetStatic.
+	Root string
+	Name string
+	Kind ChangeKind
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %q is %s", c.Root, c.Name, c.Kind)
+}
+
+// Diff compares the content of the files of the directories with the assets
+// of the package found in opts.Output, or opts.Out. Unlike Check, it doesn't
+// matter how the package is generated, only what it holds.
+func Diff(ctx context.Context, opts Options) ([]Change, error) {
+	return diff(ctx, opts, false)
+}
+
+// Verify is like Diff, and also decodes every asset of the package to make
+// sure that its content is the one recorded when it was embedded.
+func Verify(ctx context.Context, opts Options) ([]Change, error) {
+	return diff(ctx, opts, true)
+}
+
+func diff(ctx context.Context, opts Options, verify bool) ([]Change, error) {
+	g, err := newGenerator(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer g.close()
+	g.hashing = true
+
+	path := g.Output
+	if g.Out != "" {
+		path = g.Out
+	}
+	assets, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := g.snapshotRoots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the assets are named after the hashes of the names of the files when
+	// they are obfuscated, the changes after the files when they are known
+	type key struct{ root, name string }
+	files := make(map[key]entry)
+	for _, r := range roots {
+		for _, e := range r.entries {
+			name := e.Name
+			if g.Obfuscate {
+				name = obfuscate(name)
+			}
+			files[key{camelize(r.name), name}] = e
+		}
+	}
+
+	var changes []Change
+	for _, a := range assets {
+		k := key{a.Root, a.Name}
+		e, ok := files[k]
+		delete(files, k)
+		switch {
+		case !ok:
+			changes = append(changes, Change{Root: a.Root, Name: a.Name, Kind: Removed})
+			continue
+		case e.Hash != a.Hash:
+			changes = append(changes, Change{Root: a.Root, Name: e.Name, Kind: Modified})
+			continue
+		}
+		if !verify {
+			continue
+		}
+		if intact, err := a.intact(); err != nil || !intact {
+			changes = append(changes, Change{Root: a.Root, Name: e.Name, Kind: Corrupted})
+		}
+	}
+	for k, e := range files {
+		changes = append(changes, Change{Root: k.root, Name: e.Name, Kind: Added})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Root != changes[j].Root {
+			return changes[i].Root < changes[j].Root
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
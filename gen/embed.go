@@ -0,0 +1,212 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// embedDirExt ends the names of the directories holding the files copied in
+// the package directory for the embed backend.
+const embedDirExt = ".gostatic"
+
+// copies are the files copied in the package directory for the embed
+// backend, keyed by the directory holding them. Paths are slash separated,
+// and relative to the package directory.
+type copies map[string][]copyFile
+
+type copyFile struct {
+	src string
+	dst string
+}
+
+// embed finds where the files of the entries are in the package directory,
+// or copies them in it, for the embed backend. The entries are held in a
+// variable named after name.
+func (g *generator) embed(name string, dirnames []string, entries []entry) error {
+	pkgdir, err := filepath.Abs(g.Output)
+	if err != nil {
+		return err
+	}
+	dirs := make([]string, len(dirnames))
+	for i, dirname := range dirnames {
+		if dirs[i], err = filepath.Abs(dirname); err != nil {
+			return err
+		}
+	}
+	if g.copies == nil {
+		g.copies = make(copies)
+	}
+
+	embedDir := snakify(name) + embedDirExt
+	for i, e := range entries {
+		src, err := filepath.Abs(e.source)
+		if err != nil {
+			return err
+		}
+		if rel, ok := within(pkgdir, src); ok && !symlinked(pkgdir, rel) {
+			// already in the package
+			entries[i].File = rel
+		} else {
+			for j, dir := range dirs {
+				rel, ok := within(dir, src)
+				if !ok {
+					continue
+				}
+				if rel == "." {
+					// a file given on its own
+					rel = filepath.Base(src)
+				}
+				if len(dirs) > 1 {
+					// the merged directories are kept apart
+					rel = path.Join(strconv.Itoa(j+1), rel)
+				}
+				entries[i].File = path.Join(embedDir, rel)
+				break
+			}
+			g.copies[embedDir] = append(g.copies[embedDir], copyFile{src: e.source, dst: entries[i].File})
+		}
+		if strings.ContainsAny(entries[i].File, "*?[\\\"`") {
+			return fmt.Errorf("can't embed %q, its path holds special characters", e.source)
+		}
+		entries[i].FS = "embed" + camelize(name)
+	}
+	return nil
+}
+
+// within returns the slash separated path of filename relative to dir, and
+// true if it is found in dir.
+func within(dir, filename string) (string, bool) {
+	rel, err := filepath.Rel(dir, filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// symlinked tells if the path rel in dir goes through a symlink, which
+// go:embed refuses.
+func symlinked(dir, rel string) bool {
+	for name := dir; rel != ""; {
+		var elem string
+		if i := strings.Index(rel, "/"); i >= 0 {
+			elem, rel = rel[:i], rel[i+1:]
+		} else {
+			elem, rel = rel, ""
+		}
+		name = filepath.Join(name, elem)
+		if fi, err := os.Lstat(name); err != nil || fi.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// stage copies the files next to the directories holding them in dir, to be
+// put in their place by committing them. It leaves nothing behind on error.
+func (c copies) stage(dir string) (staged, error) {
+	embedDirs := make([]string, 0, len(c))
+	for embedDir := range c {
+		embedDirs = append(embedDirs, embedDir)
+	}
+	sort.Strings(embedDirs)
+
+	var s staged
+	for _, embedDir := range embedDirs {
+		tmp, err := ioutil.TempDir(dir, "."+embedDir+".tmp")
+		if err != nil {
+			s.rollback()
+			return nil, err
+		}
+		s = append(s, stagedFile{tmp: tmp, dst: filepath.Join(dir, embedDir)})
+		if err := os.Chmod(tmp, 0755); err != nil {
+			s.rollback()
+			return nil, err
+		}
+		for _, f := range c[embedDir] {
+			dst := filepath.Join(tmp, filepath.FromSlash(strings.TrimPrefix(f.dst, embedDir+"/")))
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				s.rollback()
+				return nil, err
+			}
+			if err := copyTo(dst, f.src); err != nil {
+				s.rollback()
+				return nil, fmt.Errorf("couldn't copy %q: %v", f.src, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+func copyTo(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// check compares the copies of the files found in dir with the files,
+// returning a summary of each difference.
+func (c copies) check(dir string) ([]string, error) {
+	embedDirs := make([]string, 0, len(c))
+	for embedDir := range c {
+		embedDirs = append(embedDirs, embedDir)
+	}
+	sort.Strings(embedDirs)
+
+	var diffs []string
+	for _, embedDir := range embedDirs {
+		for _, f := range c[embedDir] {
+			have, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.dst)))
+			if os.IsNotExist(err) {
+				diffs = append(diffs, fmt.Sprintf("%s: missing", f.dst))
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			want, err := ioutil.ReadFile(f.src)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(have, want) {
+				diffs = append(diffs, fmt.Sprintf("%s: changed", f.dst))
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// stale lists the directories of dir holding copies that aren't made
+// anymore.
+func (c copies) stale(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, fi := range infos {
+		if _, ok := c[fi.Name()]; !ok && fi.IsDir() && strings.HasSuffix(fi.Name(), embedDirExt) {
+			stale = append(stale, fi.Name())
+		}
+	}
+	return stale, nil
+}
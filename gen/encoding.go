@@ -0,0 +1,111 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// encoding writes the content of a file as a Go literal. The generated code
+// turns it back into bytes according to the name of the encoding.
+type encoding struct {
+	Name string
+
+	literal func(data []byte) string
+	// decode reverses the encoding, given the value of a literal.
+	decode func(value []byte) ([]byte, error)
+}
+
+var encodings = map[string]encoding{
+	"base64": {
+		Name: "base64",
+		literal: func(data []byte) string {
+			return backquote(base64.StdEncoding.EncodeToString(data))
+		},
+		decode: func(value []byte) ([]byte, error) {
+			return base64.StdEncoding.DecodeString(string(value))
+		},
+	},
+	"base256": {
+		Name: "base256",
+		literal: func(data []byte) string {
+			buf := bytes.NewBuffer(make([]byte, 0, len(data)*2))
+			for _, b := range data {
+				_, _ = buf.WriteRune('a' + rune(b))
+			}
+			return backquote(buf.String())
+		},
+		decode: func(value []byte) ([]byte, error) {
+			data := make([]byte, 0, len(value)/2)
+			for _, r := range string(value) {
+				data = append(data, byte(r-'a'))
+			}
+			return data, nil
+		},
+	},
+	"string": {
+		Name: "string",
+		literal: func(data []byte) string {
+			return strconv.Quote(string(data))
+		},
+		decode: func(value []byte) ([]byte, error) { return value, nil },
+	},
+	"bytes": {
+		Name: "bytes",
+		literal: func(data []byte) string {
+			if len(data) == 0 {
+				return "[]byte{}"
+			}
+			buf := bytes.NewBuffer(make([]byte, 0, len(data)*6+16))
+			_, _ = buf.WriteString("[]byte{")
+			for i, b := range data {
+				if i%16 == 0 {
+					_, _ = buf.WriteString("\n\t")
+				} else {
+					_ = buf.WriteByte(' ')
+				}
+				_, _ = fmt.Fprintf(buf, "0x%02x,", b)
+			}
+			_, _ = buf.WriteString("\n}")
+			return buf.String()
+		},
+		decode: func(value []byte) ([]byte, error) { return value, nil },
+	},
+}
+
+func lookupEncoding(name string) (encoding, error) {
+	e, ok := encodings[strings.ToLower(name)]
+	if !ok {
+		var names []string
+		for name := range encodings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return encoding{}, fmt.Errorf("unknown encoding %q, want one of %s", name, strings.Join(names, ", "))
+	}
+	return e, nil
+}
+
+// backquote writes s as a raw string literal when Go allows it, and as a
+// quoted string literal otherwise, such as when s holds a backtick, a
+// control character or invalid UTF-8.
+func backquote(s string) string {
+	if strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// commentSafe makes s safe to write in a line comment. It is quoted if it
+// holds anything but printable characters.
+func commentSafe(s string) string {
+	for _, r := range s {
+		if !strconv.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// adversarial returns contents that Go source can't hold verbatim, or only
+// with care.
+func adversarial() map[string][]byte {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	// a line longer than a chunk, and than gofmt aligns lists around
+	rnd := rand.New(rand.NewSource(1))
+	line := make([]byte, 3*DefaultChunkSize/2)
+	for i := range line {
+		line[i] = 'a' + byte(rnd.Intn(26))
+	}
+	return map[string][]byte{
+		"backticks.txt":    []byte("`a``b` ``` `"),
+		"nul.bin":          {0, 'a', 0, 0, 'b', 0},
+		"invalid.txt":      []byte("\xff\xfe, \xc3\x28 and \xed\xa0\x80"),
+		"bom.txt":          []byte("a\ufeffb\ufeff"),
+		"crlf.txt":         []byte("a\r\nb\rc\n"),
+		"escapes.txt":      []byte(`"\n\x00\u0000 */ // {}` + "\\"),
+		"references.txt":   []byte("\x000:5\x00 gostaticLiteral0"),
+		"all.bin":          all,
+		"empty.txt":        {},
+		"huge-line.txt":    line,
+		"a`b.txt":          []byte("named with a backtick"),
+		"gostaticLiteral1": []byte("named like a placeholder"),
+	}
+}
+
+// TestRoundTrip checks that the files are read back from the generated code
+// as they were, with every encoding and codec, in literals split in chunks or
+// not.
+func TestRoundTrip(t *testing.T) {
+	files := adversarial()
+	dir := filepath.Join(t.TempDir(), "assets")
+	writeFiles(t, dir, files)
+
+	for _, encoding := range []string{"base64", "base256", "string", "bytes"} {
+		for _, codec := range []string{"gzip", "zlib", "flate", "zstd", "none"} {
+			for _, chunkSize := range []int{0, 100, -1} {
+				if chunkSize == 100 && codec != "none" {
+					// compressed, the content is split the same way
+					continue
+				}
+				encoding, codec, chunkSize := encoding, codec, chunkSize
+				t.Run(fmt.Sprintf("%s/%s/%d", encoding, codec, chunkSize), func(t *testing.T) {
+					t.Parallel()
+					out := filepath.Join(t.TempDir(), "staticfs")
+					generate(t, Options{
+						Dirs:       []string{dir},
+						TrimPrefix: dir,
+						Output:     out,
+						Encoding:   encoding,
+						Codec:      codec,
+						ChunkSize:  chunkSize,
+						SplitSize:  -1,
+					})
+					assets, err := Load(out)
+					if err != nil {
+						t.Fatalf("couldn't load the package: %v", err)
+					}
+					if len(assets) != len(files) {
+						t.Errorf("loaded %d files, want %d", len(assets), len(files))
+					}
+					for _, a := range assets {
+						want, ok := files[a.Name]
+						if !ok {
+							t.Errorf("loaded %q, which wasn't embedded", a.Name)
+							continue
+						}
+						data, err := a.Data()
+						if err != nil {
+							t.Errorf("couldn't read %q back: %v", a.Name, err)
+						} else if !bytes.Equal(data, want) {
+							t.Errorf("%q holds %d bytes, not the %d embedded", a.Name, len(data), len(want))
+						}
+					}
+				})
+			}
+		}
+	}
+}
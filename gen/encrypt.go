@@ -0,0 +1,39 @@
+package gen
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+// DefaultKeyEnv is the environment variable the generated packages read the
+// hex encoded key of their files from, with Key.
+const DefaultKeyEnv = "GOSTATIC_KEY"
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts stored content, prefixed with its nonce. The nonce is derived
+// from the content, so that the generated code only depends on the files.
+func (g *generator) seal(stored []byte) []byte {
+	mac := hmac.New(sha256.New, g.Key)
+	_, _ = mac.Write(stored)
+	nonce := mac.Sum(nil)[:g.aead.NonceSize()]
+	return g.aead.Seal(nonce, nonce, stored, nil)
+}
+
+// unseal decrypts content encrypted by seal.
+func unseal(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	n := aead.NonceSize()
+	if len(sealed) < n {
+		return nil, fmt.Errorf("truncated content")
+	}
+	return aead.Open(nil, sealed[:n], sealed[n:], nil)
+}
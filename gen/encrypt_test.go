@@ -0,0 +1,37 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEncryptedNotCached checks that the content of encrypted files isn't
+// left on disk in the cache.
+func TestEncryptedNotCached(t *testing.T) {
+	secret := []byte("the secret held by the file")
+	dir := filepath.Join(t.TempDir(), "assets")
+	writeFiles(t, dir, map[string][]byte{"secret.txt": secret})
+	cache := filepath.Join(t.TempDir(), "assets.gc")
+
+	err := Generate(context.Background(), Options{
+		Dirs:   []string{dir},
+		Output: filepath.Join(t.TempDir(), "staticfs"),
+		Key:    bytes.Repeat([]byte{1}, 32),
+		Cache:  cache,
+		Codec:  "none",
+	})
+	if err == nil {
+		t.Error("generated encrypted files with a cache")
+	}
+	data, err := ioutil.ReadFile(cache)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, secret) {
+		t.Error("the cache holds the content of the encrypted file")
+	}
+}
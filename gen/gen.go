@@ -0,0 +1,2693 @@
+/*
+Package gen generates Go packages holding the content of directories. It is
+the library behind the gostatic command, for tools that would rather call it
+from Go than run the command.
+
+	err := gen.Generate(ctx, gen.Options{
+		Dirs:    []string{"static"},
+		PkgName: "staticfs",
+		Include: []string{"*.html", "css/*.css"},
+	})
+*/
+package gen
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"go/build/constraint"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/dustin/go-humanize"
+	"github.com/tdewolff/minify/v2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultNoCompressExt lists the extensions of the files that are typically
+// compressed already, and are stored without compression by default.
+var DefaultNoCompressExt = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".webp", ".ico",
+	".woff", ".woff2",
+	".mp3", ".mp4", ".ogg", ".webm",
+	".zip", ".gz", ".bz2", ".xz", ".br", ".zst",
+}
+
+// DefaultChunkSize is the size in bytes above which the data stored for a
+// file is split in several literals by default.
+const DefaultChunkSize = 1 << 20
+
+// DefaultSplitSize is the size in bytes above which the data of a directory
+// is split across several files by default.
+const DefaultSplitSize = 3 << 20
+
+// Options configure the generated package. The zero value of each field is
+// a sensible default.
+type Options struct {
+	// Dirs are the directories to embed. Each of them gets its own file and
+	// accessors in the package. They can also be zip or tar archives, read
+	// as the directory named after them without their extension, which the
+	// ignore files don't apply to. Archives can't be read by dev builds or
+	// used with the embed backend.
+	Dirs []string
+	// Files are files to embed on their own, exactly those, like the output
+	// of git ls-files. They share the accessors named after Name, or those
+	// of the merged directories with Merge, and are named after their clean
+	// slash separated path, like the files of directories, but aren't
+	// filtered by Include, Exclude or the ignore files. Symlinks are
+	// followed, and directories left out.
+	Files []string
+	// Remote are the URLs of zip or tar archives to download and embed like
+	// the directories they hold, named after the last element of their path
+	// without extension. A URL can end with #sha256=HEX, pinning the
+	// SHA-256 of the archive, which is an error to differ.
+	Remote []string
+	// Git are the directories of git repositories to embed, written
+	// repo@ref:path, like https://github.com/org/repo@v1.2.0:dist, and named
+	// after the last element of path, or of the repository without path.
+	// The repositories are fetched with git at ref, a tag, a branch or a
+	// commit, which can be pinned by ending them with #commit=HEX. Remote
+	// and Git can't be read by dev builds or used with the embed backend,
+	// and are given to Names as they are written.
+	Git []string
+	// PkgName is the name of the package, "staticfs" if empty. It can be
+	// the slash separated path of the package too, like
+	// "internal/assets/staticfs", which is then its Output unless set, and
+	// the package is named after its last element.
+	PkgName string
+	// SanitizePkgName turns a PkgName that isn't a valid package name, like
+	// "my assets" or "123abc", into one, "myassets" or "pkg123abc", with a
+	// warning, instead of failing.
+	SanitizePkgName bool
+	// Output is the directory the package is written to, PkgName if empty.
+	// It is created along with its parents if needed. Files generated
+	// before in it are replaced, other files are left alone.
+	Output string
+	// Out writes the package as a single file at this path, to be dropped in
+	// an existing package named PkgName, instead of a directory. It doesn't
+	// go along with Dev, and leaves the tests out.
+	Out string
+	// KeepStale keeps the files generated before in Output that aren't
+	// generated anymore, like those of a directory no longer embedded. They
+	// are removed otherwise.
+	KeepStale bool
+	// Append writes the package in the existing package found at Output,
+	// named PkgName, or after the package if empty, rather than in a
+	// package of its own. The names of the Go files end with _gostatic, so
+	// that they can't replace the files of the package, and only those are
+	// removed once stale. doc.go isn't written. It doesn't go along with
+	// Out.
+	Append bool
+
+	// HTTP generates an http.FileSystem and an http.Handler for each
+	// directory.
+	HTTP bool
+	// Precompressed keeps the gzip compressed content of the files, returned
+	// as is by GetCompressedX, and has the handlers serve it as is to the
+	// clients accepting it. It needs the gzip codec.
+	Precompressed bool
+	// Brotli also stores a Brotli compressed variant of the files that are
+	// compressible, when it is smaller, and has the handlers serve it as is
+	// to the clients accepting it. It needs HTTP.
+	Brotli bool
+	// CacheControl is the default Cache-Control header sent by the
+	// handlers, "no-cache" if empty.
+	CacheControl string
+	// Overrides also generates OverrideHandlerX, a handler serving the
+	// files found in a directory given at run time in place of the files of
+	// the same names, so that one can be patched without rebuilding. It
+	// needs HTTP.
+	Overrides bool
+	// IOFS makes the FS of each directory implement io/fs.FS, and adds
+	// DirStore and a test.
+	IOFS bool
+	// Command is the command the package is generated with, like
+	// "gostatic -pkgname staticfs static", which the documentation of the
+	// package gives, in doc.go, along with the files it holds. doc.go isn't
+	// written with Out.
+	Command string
+	// Generate is a command regenerating the package, written in a
+	// go:generate directive in doc.go, which go generate runs in the package
+	// directory.
+	Generate string
+	// Manifest writes manifest.txt along with the package, listing the
+	// name, size and SHA-256 of every file, one per line sorted by name, so
+	// that reviews of the package tell which files changed.
+	Manifest bool
+	// SelfTest generates a test making sure that every file can be read
+	// back, with the size and hash it had, in PkgName_gen_test.go. It isn't
+	// written along with Out.
+	SelfTest bool
+	// Bench generates benchmarks of the cost of decompressing the files, of
+	// GetX and of the handlers with HTTP, in PkgName_bench_test.go, to
+	// compare the codecs, encodings and backends on the files of a project.
+	// It isn't written along with Out.
+	Bench bool
+	// Merge puts the files of all the directories together, behind a single
+	// set of accessors named after Name. The same name found in two
+	// directories is an error.
+	Merge bool
+	// Name names the accessors and the file of the merged directories, and
+	// of Files, "assets" if empty.
+	Name string
+	// Names overrides the names of the accessors and the files of
+	// directories, which are named after their path otherwise. It is keyed
+	// by directory, like "my-assets": "LegacyAssets".
+	Names map[string]string
+	// MaxFileSize and MaxTotalSize are the sizes in bytes, before
+	// compression, that a file and all the files together mustn't exceed,
+	// if not zero. Exceeding them is an error found before any file is
+	// read, or a warning if BudgetWarn is set.
+	MaxFileSize  int64
+	MaxTotalSize int64
+	BudgetWarn   bool
+	// OnError tells what to do with the files that can't be read: "fail",
+	// the default, aborts without writing anything, while "skip" leaves them
+	// out of the package, logging each of them along with a summary.
+	OnError string
+	// Symlinks tells what to do with symlinks: "skip" them, the default,
+	// "follow" them, or report them as an "error". Symlinks leading back to
+	// a directory holding them are an error when followed.
+	Symlinks string
+	// Normalize tells how the names of the files are normalized: "none",
+	// the default, keeps them as they are, "nfc" writes them in Unicode NFC,
+	// so that the names of files created on macOS, which are decomposed,
+	// match the ones written elsewhere, and "nfc-lookups" also normalizes
+	// the names the functions are given, which then need
+	// golang.org/x/text.
+	Normalize string
+	// Lazy decompresses each file on first access instead of at init.
+	Lazy bool
+	// Release generates ReleaseX and PurgeX, which drop the decompressed
+	// content of the files, to free memory. It is decompressed again the
+	// next time it is needed.
+	Release bool
+	// MemoryBudget bounds the size in bytes of the decompressed content
+	// kept in memory, if positive: the content of the least recently used
+	// files is released to stay within it, and decompressed again when
+	// needed. The generated package can change it at run time. It implies
+	// Lazy and Release.
+	MemoryBudget int64
+	// Dev generates code reading the directories from disk, built with the
+	// dev build tag.
+	Dev bool
+
+	// Codec compresses the files: "gzip" if empty, "zlib", "flate", "zstd"
+	// or "none".
+	Codec string
+	// Level is the compression level, from 1 (fastest) to 9 (best), or 0
+	// for the default level of the codec.
+	Level int
+	// Encoding writes the data in Go source: "base64" if empty, "base256",
+	// "string" or "bytes".
+	Encoding string
+	// NoCompressExt are the extensions of the files to store without
+	// compression, DefaultNoCompressExt if nil.
+	NoCompressExt []string
+	// Pipe are rules written `glob=command`, running the files matching the
+	// glob through the command, and embedding its output instead. The globs
+	// are matched like Templates, and the first matching rule applies. The
+	// command is run by the shell, with the content of the file on its
+	// standard input and its path in GOSTATIC_FILE. It can't be used with
+	// the embed backend.
+	Pipe []string
+	// Cache is a file keeping what is stored for each file between runs,
+	// so that the files that didn't change aren't minified and compressed
+	// again, if set. Only Generate writes it.
+	Cache string
+	// Solid concatenates the compressible files smaller than Solid bytes in
+	// blocks compressed as a single stream, which compresses many small
+	// files much better than one by one. Reading one of them decompresses
+	// its whole block, once. It can't be used with the embed backend.
+	Solid int64
+	// InlineThreshold keeps the files stored in fewer bytes in Go literals
+	// with the pak backend, only the larger ones going to the pak.
+	InlineThreshold int64
+	// Key encrypts the stored content of the files with AES-GCM, so that it
+	// can't be read from the binary, with a key of 16, 24 or 32 bytes. The
+	// generated package reads it hex encoded from the environment variable
+	// KeyEnv, DefaultKeyEnv if empty, unless it is given to SetKey, and
+	// decrypts each file on first access. It can't be used with Cache, which
+	// keeps the content of the files unencrypted.
+	Key    []byte
+	KeyEnv string
+	// SignKey signs a manifest of the name, size and SHA-256 of the files of
+	// each directory, which the generated package checks at init, along with
+	// the content of each file when it is read, panicking if it doesn't
+	// match.
+	SignKey ed25519.PrivateKey
+	// Minify minifies the HTML, CSS, JavaScript, SVG and JSON files before
+	// compressing them, after Pipe. It can't be used with the embed backend.
+	Minify bool
+	// Gunzip embeds the files ending with .gz, which must be gzip streams,
+	// as the content they hold, under their name without .gz. With the gzip
+	// codec, their stream is stored as it is rather than compressed again.
+	// It can't be used with the embed backend.
+	Gunzip bool
+
+	// Include are the globs of the files to embed, all of them if empty.
+	// Globs follow the syntax of path.Match, plus `**` matching any number
+	// of directories, and are matched against the slash separated path of
+	// each file relative to its directory.
+	Include []string
+	// Exclude are the globs of the files and directories to skip.
+	Exclude []string
+	// Templates are the globs of the files that TemplatesX parses, as
+	// text/template templates or html/template ones with HTMLTemplates.
+	// They are matched against the names of the files, or against their
+	// last element for the globs without a slash, like "*.tmpl".
+	Templates     []string
+	HTMLTemplates bool
+	// Locales are the globs of the translation catalogs, like
+	// "locales/*.json", matched like Templates. Each is named after its
+	// language tag, like fr or pt-BR, and LocalesX and LocaleX list them
+	// and find the best one for a tag with golang.org/x/text/language,
+	// falling back on DefaultLocale if set.
+	Locales       []string
+	DefaultLocale string
+	// TrimPrefix is removed from the start of the names of the files, which
+	// start with their directory otherwise.
+	TrimPrefix string
+	// Rewrite are rules renaming the files, written `old=>new`. The first
+	// rule whose old prefix starts the name of a file, once trimmed of
+	// TrimPrefix, replaces that prefix by new.
+	Rewrite []string
+	// NoIgnore embeds the files listed in .gitignore and .gostaticignore.
+	NoIgnore bool
+	// ModTime records the modification time of the files. It is left out
+	// otherwise, since it differs between checkouts of the same files, so
+	// that the package only depends on their content and mode.
+	ModTime bool
+	// EmptyDirs keeps the directories holding nothing, which are otherwise
+	// left out, since only the files are embedded. The functions reading
+	// directories list them, and extract creates them.
+	EmptyDirs bool
+	// Fingerprint adds the start of the hash of each file to its name, like
+	// app.3f9ab2c1.css, so that the names change along with the content.
+	// HTML pages keep their name, as they are the ones linking to the
+	// others. The accessors then include a manifest of the names.
+	Fingerprint bool
+	// Obfuscate replaces the names of the files in the generated code with
+	// opaque hashes, so that the binary doesn't tell the layout of the
+	// directories. The files are still looked up by name, but can't be
+	// listed, which leaves out the functions listing them, and can't be used
+	// with HTTP, IOFS, Templates or Fingerprint.
+	Obfuscate bool
+	// IgnoreCase has the functions find the files whose names only differ
+	// by case from the names they are given, when they don't find them as
+	// they are, like on the file systems of macOS and Windows. Two files of
+	// a directory can't have names only differing by case then.
+	IgnoreCase bool
+	// Integrity computes the Subresource Integrity value of the scripts and
+	// style sheets, for the integrity attribute of the elements loading
+	// them.
+	Integrity bool
+	// Extract generates ExtractToDirX and ExtractFileX, writing the files
+	// back to disk with their modes, like helper programs to run: "none",
+	// the default, doesn't, "always" writes every file, and "changed"
+	// leaves the files already holding the content they would be written
+	// with, from their hash.
+	Extract string
+
+	// Backend is how the content of the files is stored: "literal" if
+	// empty, in Go literals, or "embed", in files of the package directory
+	// embedded with go:embed. Files found outside of the package directory
+	// are copied in it, in a directory named after their root with a
+	// .gostatic extension. The embed backend stores files as they are, and
+	// reads them on first access. The "bundle" backend stores them one after
+	// the other in a single file per root, embedded in a string which the
+	// assets slice, so that nothing is copied until they are decompressed.
+	// The "pak" backend stores them in a .pak file per root, written to the
+	// package directory but not embedded, to be shipped next to the
+	// executable, which reads it on first access.
+	Backend string
+
+	// Tags is a build constraint added to every file of the package but
+	// doc.go, like "embed_assets" or "full && !lite", so that a build picks
+	// one of several packages.
+	Tags string
+
+	// Template is the path of a file holding a custom template for the file
+	// of each root, replacing the built-in one. It is parsed along with the
+	// built-in templates, and can use or redefine them, like "data" which
+	// declares the assets. The README documents what it is executed with.
+	Template string
+
+	// ChunkSize is the size in bytes above which the data stored for a file
+	// is split in several literals, which keeps the compiler fast. It is
+	// DefaultChunkSize if 0, and files are never split if it is negative.
+	ChunkSize int
+
+	// SplitSize is the size in bytes above which the data of a directory is
+	// split across several files, so that they compile in parallel and open
+	// in editors. It is DefaultSplitSize if 0, and the data is never split
+	// if it is negative or with Out.
+	SplitSize int
+
+	// Jobs is the number of files read and compressed at once,
+	// runtime.NumCPU() if 0.
+	Jobs int
+
+	// Log receives a line for each file embedded, nothing is logged if nil.
+	Log *log.Logger
+	// Verbose logs more details to Log, like the files left out and why.
+	Verbose bool
+	// Progress receives a progress bar of the files of each directory read
+	// and compressed, in place of the line logged for each, when there are
+	// at least ProgressMin of them. It is meant for a terminal.
+	Progress    io.Writer
+	ProgressMin int
+	// ErrorLog receives the errors that don't stop the generation, nothing
+	// is logged if nil.
+	ErrorLog *log.Logger
+	// Report receives a Report of the generation as JSON once the package
+	// is written, if set.
+	Report io.Writer
+	// Stats logs a summary of the sizes once the package is written, with
+	// the Stats extensions and files storing the most, if above 0.
+	Stats int
+}
+
+// Generate writes the package holding the directories to opts.Output. Unless
+// opts.OnError is "skip", nothing is written if any file can't be read. If
+// ctx is done first, the files written aside are removed and an *Interrupted
+// error is returned.
+func Generate(ctx context.Context, opts Options) error {
+	return GenerateAll(ctx, []Options{opts})
+}
+
+// GenerateAll writes several packages, each like Generate does. They are all
+// generated, then all written aside, before any of them is put in place, so
+// that a failure leaves every package as it was. Two packages can't be
+// written to the same place.
+func GenerateAll(ctx context.Context, opts []Options) error {
+	pkgs := make([]*pending, 0, len(opts))
+	defer func() {
+		for _, p := range pkgs {
+			p.g.close()
+		}
+	}()
+	written := make(map[string]string)
+	// the packages sharing a cache file share the cache, which saves what
+	// every one of them used
+	caches := make(map[string]*cache)
+	for _, o := range opts {
+		g, err := newGenerator(o)
+		if err != nil {
+			return err
+		}
+		p := &pending{g: g, start: time.Now()}
+		pkgs = append(pkgs, p)
+		dst := g.Output
+		if g.Out != "" {
+			dst = g.Out
+		}
+		dst = filepath.Clean(dst)
+		if other, ok := written[dst]; ok {
+			return fmt.Errorf("packages %q and %q are both written to %s", other, g.PkgName, dst)
+		}
+		written[dst] = g.PkgName
+		if g.cache != nil {
+			if c, ok := caches[filepath.Clean(g.Cache)]; ok {
+				g.cache, p.sharedCache = c, true
+			} else {
+				caches[filepath.Clean(g.Cache)] = g.cache
+			}
+		}
+	}
+
+	for _, p := range pkgs {
+		g := p.g
+		g.vlogf("Compressing with %s on %d workers, writing with the %s encoding",
+			g.codec.Name, g.jobs, g.encoding.Name)
+		var err error
+		if p.out, err = g.generate(ctx); err != nil {
+			return err
+		}
+	}
+
+	// every file is written aside first, so that a failure leaves the
+	// packages as they were
+	rollback := func(pkgs []*pending) {
+		for _, p := range pkgs {
+			p.rollback()
+		}
+	}
+	for i, p := range pkgs {
+		if err := p.stage(); err != nil {
+			rollback(pkgs[:i])
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		rollback(pkgs)
+		return &Interrupted{Err: err}
+	}
+	for i, p := range pkgs {
+		if err := p.files.commit(); err != nil {
+			// the packages before are in place already
+			p.files = nil
+			rollback(pkgs[i:])
+			return fmt.Errorf("couldn't write package: %v", err)
+		}
+	}
+	for _, p := range pkgs {
+		if err := p.finish(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pending is a generated package on its way to its directory.
+type pending struct {
+	g     *generator
+	out   generated
+	start time.Time
+	// files are the files written aside, and created tells if the
+	// directory of the package was created for them
+	files   staged
+	created bool
+	// sharedCache tells if the cache is saved with another package
+	sharedCache bool
+}
+
+// stage writes the files of the package aside, creating its directory if
+// needed.
+func (p *pending) stage() error {
+	g := p.g
+	if _, err := os.Stat(g.Output); os.IsNotExist(err) {
+		if err := os.MkdirAll(g.Output, 0755); err != nil {
+			return fmt.Errorf("couldn't create package directory: %v", err)
+		}
+		p.created = true
+	} else if err != nil {
+		return fmt.Errorf("couldn't open package directory: %v", err)
+	}
+	files, err := p.out.stage(g.Output)
+	if err != nil {
+		p.rollback()
+		return fmt.Errorf("couldn't write package: %v", err)
+	}
+	copied, err := g.copies.stage(g.Output)
+	if err != nil {
+		files.rollback()
+		p.rollback()
+		return fmt.Errorf("couldn't copy files to embed: %v", err)
+	}
+	p.files = append(files, copied...)
+	return nil
+}
+
+// rollback removes the files written aside, and the directory of the package
+// if it was created for them.
+func (p *pending) rollback() {
+	p.files.rollback()
+	p.files = nil
+	if p.created {
+		_ = os.Remove(p.g.Output)
+	}
+}
+
+// finish removes the stale files of the package once it is in place, saves
+// the cache and reports what was generated.
+func (p *pending) finish() error {
+	g, out := p.g, p.out
+	if p.created {
+		if g.report.ImportPath != "" {
+			g.logf("Created directory %q for package %q, imported as %q", g.Output, g.PkgName, g.report.ImportPath)
+		} else {
+			g.logf("Created directory %q for package %q", g.Output, g.PkgName)
+		}
+	}
+	if !g.KeepStale {
+		stale, err := out.stale(g.Output, g.suffix)
+		if err != nil {
+			return fmt.Errorf("couldn't look for stale files: %v", err)
+		}
+		for _, filename := range stale {
+			if err := os.Remove(filepath.Join(g.Output, filename)); err != nil {
+				return fmt.Errorf("couldn't remove stale file: %v", err)
+			}
+			g.logf("Removed %q, which is no longer generated", filepath.Join(g.Output, filename))
+		}
+		stale, err = g.copies.stale(g.Output)
+		if err != nil {
+			return fmt.Errorf("couldn't look for stale files: %v", err)
+		}
+		for _, dirname := range stale {
+			if err := os.RemoveAll(filepath.Join(g.Output, dirname)); err != nil {
+				return fmt.Errorf("couldn't remove stale files: %v", err)
+			}
+			g.logf("Removed %q, which is no longer embedded", filepath.Join(g.Output, dirname))
+		}
+	}
+	if g.cache != nil && !p.sharedCache {
+		if err := g.cache.save(); err != nil {
+			return fmt.Errorf("couldn't save cache: %v", err)
+		}
+	}
+	g.summarize()
+	g.report.Skipped = g.skipped
+	g.report.finish()
+	if g.Stats > 0 {
+		for _, line := range g.report.Summary(g.Stats) {
+			g.logf("%s", line)
+		}
+	}
+	if g.Report != nil {
+		if err := g.report.write(g.Report); err != nil {
+			return fmt.Errorf("couldn't write report: %v", err)
+		}
+	}
+	g.vlogf("Generated package %q in %s", g.PkgName, time.Since(p.start).Round(time.Millisecond))
+	return nil
+}
+
+// DryRun reads and compresses the files of the directories like Generate, and
+// returns the Report of what would be generated, without writing anything.
+// The report is also written to opts.Report, if set.
+func DryRun(ctx context.Context, opts Options) (*Report, error) {
+	g, err := newGenerator(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer g.close()
+	g.dryRun = true
+
+	roots, err := g.snapshotRoots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roots {
+		if !g.embedding {
+			g.dedup(r.entries, "shared")
+		}
+		g.report.add(camelize(r.name), r.entries)
+	}
+	g.summarize()
+	g.report.Skipped = g.skipped
+	g.report.finish()
+	if g.Report != nil {
+		if err := g.report.write(g.Report); err != nil {
+			return nil, fmt.Errorf("couldn't write report: %v", err)
+		}
+	}
+	return &g.report, nil
+}
+
+// Check generates the package holding the directories in memory, and
+// compares it with the one found in opts.Output. It returns a summary of each
+// difference, none meaning that the package is up to date.
+func Check(ctx context.Context, opts Options) ([]string, error) {
+	g, err := newGenerator(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer g.close()
+	g.checking = true
+
+	out, err := g.generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diffs, err := out.check(g.Output, g.KeepStale, g.suffix)
+	if err != nil {
+		return nil, err
+	}
+	copied, err := g.copies.check(g.Output)
+	if err != nil {
+		return nil, err
+	}
+	diffs = append(diffs, copied...)
+	if !g.KeepStale {
+		stale, err := g.copies.stale(g.Output)
+		if err != nil {
+			return nil, err
+		}
+		for _, dirname := range stale {
+			diffs = append(diffs, fmt.Sprintf("%s: no longer embedded", dirname))
+		}
+	}
+	return diffs, nil
+}
+
+// generator holds the options once validated.
+type generator struct {
+	Options
+
+	codec     codec
+	level     int
+	encoding  encoding
+	include   globs
+	exclude   globs
+	templates globs
+	locales   globs
+	renamer   renamer
+	jobs      int
+	chunkSize int
+	splitSize int
+	splitting bool
+	// solidifying is set once a root has blocks, with Solid
+	solidifying bool
+	hashing     bool
+	embedding   bool
+	bundling    bool
+	paking      bool
+	aead        cipher.AEAD
+	copies      copies
+	spool       *spool
+	checking    bool
+	dryRun      bool
+	report      Report
+	filetempl   *template.Template
+	minifier    *minify.M
+	cache       *cache
+	pipes       []pipe
+	// progress is the bar of the directory being compressed, if shown
+	progress *progress
+	build    constraint.Expr
+	names    map[string]string
+	// sources are Dirs followed by Remote and Git, which remotes holds
+	sources []string
+	remotes map[string]*remote
+	// tmpdir holds the remote sources fetched
+	tmpdir string
+	// manifest lists the files embedded so far, with Manifest
+	manifest []string
+	// suffix ends the names of the Go files, with Append
+	suffix string
+	// emptyDirs are the names of the empty directories found by the last
+	// snapshot, with EmptyDirs
+	emptyDirs []string
+
+	mu      sync.Mutex
+	skipped []string
+	// total is the size of the files found so far, checked against
+	// MaxTotalSize
+	total     int64
+	overTotal bool
+}
+
+func newGenerator(opts Options) (*generator, error) {
+	g := &generator{Options: opts}
+	if strings.ContainsRune(g.PkgName, '/') || strings.ContainsRune(g.PkgName, filepath.Separator) {
+		dir := filepath.Clean(filepath.FromSlash(g.PkgName))
+		if g.Output == "" {
+			g.Output = dir
+		}
+		g.PkgName = filepath.Base(dir)
+	}
+	if g.PkgName != "" && !validPkgName(g.PkgName) {
+		if !g.SanitizePkgName {
+			return nil, fmt.Errorf("invalid package name %q, %s", g.PkgName, whyInvalid(g.PkgName))
+		}
+		name := sanitizePkgName(g.PkgName)
+		g.errorf("Warning: named the package %q rather than %q, %s", name, g.PkgName, whyInvalid(g.PkgName))
+		g.PkgName = name
+	}
+	if g.Append {
+		if g.Out != "" {
+			return nil, fmt.Errorf("a single file can't be appended to a package, it is written in one already")
+		}
+		if g.Output == "" {
+			return nil, fmt.Errorf("appending to a package needs its directory")
+		}
+		name, err := packageName(g.Output)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find the package to append to: %v", err)
+		}
+		if g.PkgName != "" && g.PkgName != name {
+			return nil, fmt.Errorf("the package in %s is named %s, not %s", g.Output, name, g.PkgName)
+		}
+		g.PkgName = name
+		g.suffix = appendSuffix
+	}
+	if g.PkgName == "" {
+		g.PkgName = "staticfs"
+	}
+	if g.Output == "" {
+		g.Output = g.PkgName
+	}
+	if g.Name == "" {
+		g.Name = "assets"
+	}
+	if g.CacheControl == "" {
+		g.CacheControl = "no-cache"
+	}
+	switch g.Backend {
+	case "", "literal":
+	case "embed":
+		if g.Codec != "" && g.Codec != "none" {
+			return nil, fmt.Errorf("the embed backend stores files as they are, it can't use the %s codec", g.Codec)
+		}
+		g.Codec = "none"
+		g.Lazy = true
+		g.embedding = true
+	case "bundle":
+		if g.Encoding != "" && g.Encoding != "string" {
+			return nil, fmt.Errorf("the bundle backend stores files in their pack, it can't use the %s encoding", g.Encoding)
+		}
+		g.Encoding = "string"
+		g.Lazy = true
+		g.bundling = true
+	case "pak":
+		g.Lazy = true
+		g.paking = true
+	default:
+		return nil, fmt.Errorf("unknown backend %q, want literal, embed, bundle or pak", g.Backend)
+	}
+	if g.MemoryBudget > 0 {
+		g.Lazy = true
+		g.Release = true
+	}
+	switch g.OnError {
+	case "":
+		g.OnError = "fail"
+	case "fail", "skip":
+	default:
+		return nil, fmt.Errorf("unknown error policy %q, want skip or fail", g.OnError)
+	}
+	switch g.Symlinks {
+	case "":
+		g.Symlinks = "skip"
+	case "skip", "follow", "error":
+	default:
+		return nil, fmt.Errorf("unknown symlinks policy %q, want skip, follow or error", g.Symlinks)
+	}
+	switch g.Extract {
+	case "":
+		g.Extract = "none"
+	case "none", "always", "changed":
+	default:
+		return nil, fmt.Errorf("unknown extraction %q, want none, always or changed", g.Extract)
+	}
+	switch g.Normalize {
+	case "":
+		g.Normalize = "none"
+	case "none", "nfc", "nfc-lookups":
+	default:
+		return nil, fmt.Errorf("unknown normalization %q, want none, nfc or nfc-lookups", g.Normalize)
+	}
+	if g.Codec == "" {
+		g.Codec = "gzip"
+	}
+	if g.Encoding == "" {
+		g.Encoding = "base64"
+	}
+	if g.NoCompressExt == nil {
+		g.NoCompressExt = DefaultNoCompressExt
+	}
+	g.sources = append([]string(nil), g.Dirs...)
+	g.remotes = make(map[string]*remote)
+	for _, spec := range g.Remote {
+		r, err := parseRemote(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote: %v", err)
+		}
+		g.sources, g.remotes[spec] = append(g.sources, spec), r
+	}
+	for _, spec := range g.Git {
+		r, err := parseGit(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid git source: %v", err)
+		}
+		g.sources, g.remotes[spec] = append(g.sources, spec), r
+	}
+	if len(g.remotes) != 0 {
+		if g.Dev {
+			return nil, fmt.Errorf("the dev builds read directories from disk, they can't read remote sources")
+		}
+		if g.Backend == "embed" {
+			return nil, fmt.Errorf("the embed backend embeds files from disk, it can't embed those of remote sources")
+		}
+	}
+	if len(g.sources) == 0 && len(g.Files) == 0 {
+		return nil, fmt.Errorf("need at least one directory or file")
+	}
+	for _, dirname := range g.Dirs {
+		if !isArchive(dirname) {
+			continue
+		}
+		if g.Dev {
+			return nil, fmt.Errorf("the dev builds read directories from disk, they can't read %q", dirname)
+		}
+		if g.Backend == "embed" {
+			return nil, fmt.Errorf("the embed backend embeds files from disk, it can't embed those of %q", dirname)
+		}
+	}
+	if g.Out != "" {
+		if g.Dev {
+			return nil, fmt.Errorf("a single file can't hold the dev builds")
+		}
+		// the other files of the package aren't ours
+		g.Output = filepath.Dir(g.Out)
+		g.KeepStale = true
+	}
+
+	var err error
+	if g.codec, err = lookupCodec(g.Codec); err != nil {
+		return nil, err
+	}
+	if g.Level < 0 || g.Level > flate.BestCompression {
+		return nil, fmt.Errorf("invalid level %d, want 1 to 9 or 0 for the default", g.Level)
+	}
+	g.jobs = g.Jobs
+	if g.jobs == 0 {
+		g.jobs = runtime.NumCPU()
+	} else if g.jobs < 0 {
+		return nil, fmt.Errorf("invalid number of jobs %d", g.jobs)
+	}
+	g.chunkSize = g.ChunkSize
+	if g.chunkSize == 0 {
+		g.chunkSize = DefaultChunkSize
+	}
+	g.splitSize = g.SplitSize
+	if g.splitSize == 0 {
+		g.splitSize = DefaultSplitSize
+	}
+	if g.Out != "" || g.embedding || g.bundling {
+		g.splitSize = -1
+	}
+	if g.bundling {
+		g.chunkSize = -1
+	}
+	g.level = g.Level
+	if g.level == 0 {
+		g.level = flate.DefaultCompression
+	}
+	if g.encoding, err = lookupEncoding(g.Encoding); err != nil {
+		return nil, err
+	}
+	if g.Precompressed && g.codec.Name != "gzip" {
+		return nil, fmt.Errorf("keeping precompressed content needs the gzip codec")
+	}
+	if g.Obfuscate && (g.HTTP || g.IOFS || g.Fingerprint || len(g.Templates) != 0 || len(g.Locales) != 0) {
+		return nil, fmt.Errorf("obfuscated names can't be listed, for the http handlers, io/fs, fingerprints, templates or locales")
+	}
+	if g.Brotli && !g.HTTP {
+		return nil, fmt.Errorf("serving brotli variants needs the http handlers")
+	}
+	if g.Overrides && !g.HTTP {
+		return nil, fmt.Errorf("serving overrides needs the http handlers")
+	}
+	if g.include, err = parseGlobs(g.Include); err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %v", err)
+	}
+	if g.exclude, err = parseGlobs(g.Exclude); err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %v", err)
+	}
+	if g.templates, err = parseGlobs(g.Templates); err != nil {
+		return nil, fmt.Errorf("invalid templates pattern: %v", err)
+	}
+	if g.locales, err = parseGlobs(g.Locales); err != nil {
+		return nil, fmt.Errorf("invalid locales pattern: %v", err)
+	}
+	if g.DefaultLocale != "" && !isLanguageTag(g.DefaultLocale) {
+		return nil, fmt.Errorf("the default locale %q isn't a language tag, like en or pt-BR", g.DefaultLocale)
+	}
+	if g.Cache != "" {
+		if g.cache, err = loadCache(g.Cache); err != nil {
+			return nil, err
+		}
+	}
+	if g.pipes, err = parsePipes(g.Pipe); err != nil {
+		return nil, fmt.Errorf("invalid pipe: %v", err)
+	}
+	if len(g.pipes) != 0 && g.embedding {
+		return nil, fmt.Errorf("the embed backend embeds files as they are, it can't pipe them through commands")
+	}
+	if g.Minify {
+		if g.embedding {
+			return nil, fmt.Errorf("the embed backend embeds files as they are, it can't minify them")
+		}
+		g.minifier = newMinifier()
+	}
+	if g.Brotli && g.embedding {
+		return nil, fmt.Errorf("the embed backend embeds files as they are, it can't store brotli variants")
+	}
+	if g.Solid > 0 && g.embedding {
+		return nil, fmt.Errorf("the embed backend embeds files as they are, it can't put them in blocks")
+	}
+	if g.SignKey != nil && len(g.SignKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid signing key of %d bytes", len(g.SignKey))
+	}
+	if g.Key != nil {
+		if g.embedding {
+			return nil, fmt.Errorf("the embed backend embeds files as they are, it can't encrypt them")
+		}
+		if g.Cache != "" {
+			return nil, fmt.Errorf("the cache keeps the content of the files unencrypted, it can't be used with encryption")
+		}
+		if g.aead, err = newAEAD(g.Key); err != nil {
+			return nil, fmt.Errorf("invalid key: %v", err)
+		}
+		if g.KeyEnv == "" {
+			g.KeyEnv = DefaultKeyEnv
+		}
+		// the key may only be given once the package is initialized
+		g.Lazy = true
+	}
+	if g.InlineThreshold > 0 && !g.paking {
+		return nil, fmt.Errorf("an inline threshold needs the pak backend")
+	}
+	if g.Gunzip && g.embedding {
+		return nil, fmt.Errorf("the embed backend embeds files as they are, it can't gunzip them")
+	}
+	if g.renamer, err = parseRenamer(g.TrimPrefix, g.Rewrite); err != nil {
+		return nil, fmt.Errorf("invalid rewrite: %v", err)
+	}
+	if g.Tags != "" {
+		if g.build, err = constraint.Parse("//go:build " + g.Tags); err != nil {
+			return nil, fmt.Errorf("invalid tags %q: %v", g.Tags, err)
+		}
+	}
+	g.filetempl = filetempl
+	if g.Template != "" {
+		if g.filetempl, err = parseTemplate(g.Template); err != nil {
+			return nil, fmt.Errorf("invalid template: %v", err)
+		}
+	}
+	if err := g.checkNames(); err != nil {
+		return nil, err
+	}
+	g.spool = newSpool()
+	g.report = Report{Package: g.PkgName, ImportPath: importPath(g.Output), Codec: g.codec.Name, Encoding: g.encoding.Name}
+	return g, nil
+}
+
+// close releases the resources held by the generator.
+func (g *generator) close() {
+	if err := g.spool.close(); err != nil {
+		g.errorf("Couldn't remove spool: %v", err)
+	}
+	if g.tmpdir != "" {
+		if err := os.RemoveAll(g.tmpdir); err != nil {
+			g.errorf("Couldn't remove remote sources: %v", err)
+		}
+	}
+}
+
+func (g *generator) logf(format string, args ...interface{}) {
+	if g.Log != nil {
+		g.Log.Printf(format, args...)
+	}
+}
+
+// vlogf logs details only wanted with Verbose.
+func (g *generator) vlogf(format string, args ...interface{}) {
+	if g.Verbose {
+		g.logf(format, args...)
+	}
+}
+
+// filelogf logs the line of a file, unless a progress bar shows instead.
+func (g *generator) filelogf(format string, args ...interface{}) {
+	if g.progress == nil {
+		g.logf(format, args...)
+	}
+}
+
+func (g *generator) errorf(format string, args ...interface{}) {
+	if g.ErrorLog == nil {
+		return
+	}
+	if g.progress != nil {
+		g.progress.interrupt(func() { g.ErrorLog.Printf(format, args...) })
+		return
+	}
+	g.ErrorLog.Printf(format, args...)
+}
+
+// Interrupted is the error returned when the context is done before the
+// generation is, in which case nothing is written.
+type Interrupted struct {
+	// File is the file being read or compressed when interrupted, if any.
+	File string
+	Err  error
+}
+
+func (e *Interrupted) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("interrupted: %v", e.Err)
+	}
+	return fmt.Sprintf("interrupted while processing %q: %v", e.File, e.Err)
+}
+
+func (e *Interrupted) Unwrap() error { return e.Err }
+
+// failed handles the error met reading the file called name. It is returned,
+// to abort the generation, unless the file is to be skipped.
+func (g *generator) failed(name string, err error) error {
+	if g.OnError != "skip" {
+		return err
+	}
+	g.errorf("Skipped %q: %v", name, err)
+	g.mu.Lock()
+	g.skipped = append(g.skipped, name)
+	g.mu.Unlock()
+	return nil
+}
+
+// budget counts a file of size bytes against the size budgets. Exceeding one
+// is an error, unless BudgetWarn is set.
+func (g *generator) budget(name string, size int64) error {
+	var over []string
+	if g.MaxFileSize > 0 && size > g.MaxFileSize {
+		over = append(over, fmt.Sprintf("%q is %s, more than the maximum of %s per file",
+			name, humanize.Bytes(uint64(size)), humanize.Bytes(uint64(g.MaxFileSize))))
+	}
+	g.total += size
+	if g.MaxTotalSize > 0 && g.total > g.MaxTotalSize && !g.overTotal {
+		g.overTotal = true
+		over = append(over, fmt.Sprintf("the files add up to more than the maximum of %s with %q",
+			humanize.Bytes(uint64(g.MaxTotalSize)), name))
+	}
+	if len(over) == 0 {
+		return nil
+	}
+	if !g.BudgetWarn {
+		return fmt.Errorf("%s", strings.Join(over, ", and "))
+	}
+	for _, msg := range over {
+		g.errorf("Warning: %s", msg)
+	}
+	return nil
+}
+
+// filter records a file, or a directory, left out for reason.
+func (g *generator) filter(name string, isDir bool, reason string) {
+	g.vlogf("Left out %q, %s", name, reason)
+	g.report.filter(name, isDir, reason)
+}
+
+// summarize logs the files that were skipped.
+func (g *generator) summarize() {
+	if len(g.skipped) == 0 {
+		return
+	}
+	sort.Strings(g.skipped)
+	quoted := make([]string, len(g.skipped))
+	for i, name := range g.skipped {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	g.errorf("Skipped %d files that couldn't be read: %s", len(g.skipped), strings.Join(quoted, ", "))
+}
+
+// generate creates the files of the package.
+func (g *generator) generate(ctx context.Context) (generated, error) {
+	out := make(generated)
+
+	if !g.Obfuscate {
+		// the FS type needs the names of the files
+		g.writeSupportFile(out, "fs.go", fstempl)
+	}
+	if g.HTTP {
+		g.writeSupportFile(out, "http_fs.go", httptempl)
+	}
+	if g.IOFS {
+		g.writeSupportFile(out, "io_fs.go", iofstempl)
+	}
+	if g.Dev || g.IOFS {
+		// dev.go also reads the directories given to DirStore
+		g.writeSupportFile(out, "dev.go", devtempl)
+	}
+
+	roots, err := g.snapshotRoots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roots {
+		if err := ctx.Err(); err != nil {
+			return nil, &Interrupted{Err: err}
+		}
+		if err := g.writeRoot(out, r.name, r.dirnames, r.entries, r.emptyDirs); err != nil {
+			return nil, fmt.Errorf("couldn't write %q: %v", r.name, err)
+		}
+	}
+	// the common file depends on how the roots were written
+	g.writeCommonFile(out)
+	if g.Manifest {
+		out[manifestFile] = g.writeManifest
+	}
+	if g.Out == "" && !g.Append {
+		g.writeDocFile(out)
+	}
+	if g.SelfTest {
+		rootNames := make([]string, len(roots))
+		for i, r := range roots {
+			rootNames[i] = camelize(r.name)
+		}
+		out.execute(snakify(g.PkgName)+"_gen_test.go", gentesttempl, struct {
+			PkgName string
+			Dev     bool
+			Release bool
+			Roots   []string
+		}{
+			PkgName: g.PkgName,
+			Dev:     g.Dev,
+			Release: g.Release,
+			Roots:   rootNames,
+		})
+	}
+	if g.Bench {
+		rootNames := make([]string, len(roots))
+		for i, r := range roots {
+			rootNames[i] = camelize(r.name)
+		}
+		out.execute(snakify(g.PkgName)+"_bench_test.go", benchtempl, struct {
+			PkgName   string
+			Dev       bool
+			Release   bool
+			HTTP      bool
+			Obfuscate bool
+			Roots     []string
+		}{
+			PkgName:   g.PkgName,
+			Dev:       g.Dev,
+			Release:   g.Release,
+			HTTP:      g.HTTP,
+			Obfuscate: g.Obfuscate,
+			Roots:     rootNames,
+		})
+	}
+
+	if g.Out != "" {
+		out = out.single(filepath.Base(g.Out), g.PkgName)
+	}
+	if g.build != nil {
+		for filename, r := range out {
+			// the documentation of the package is left to every build,
+			// which would otherwise lose it, or get an empty package
+			if filepath.Ext(filename) != ".go" || filename == "doc.go" {
+				continue
+			}
+			out[filename] = constrain(r, g.build)
+		}
+	}
+	if g.suffix != "" {
+		out = out.suffixed(g.suffix)
+	}
+	return out, nil
+}
+
+// reservedFiles are the files of the package that aren't named after a root.
+var reservedFiles = []string{"gostatic.go", "doc.go", "http_fs.go", "io_fs.go", "dev.go"}
+
+// checkNames makes sure that the roots get distinct identifiers and files,
+// which their names could map to the same way.
+func (g *generator) checkNames() error {
+	g.names = make(map[string]string, len(g.Names))
+	for dirname, name := range g.Names {
+		if g.Merge {
+			return fmt.Errorf("the names of merged directories can't be overridden, they share %q", g.Name)
+		}
+		found := false
+		for _, d := range g.sources {
+			found = found || filepath.Clean(d) == filepath.Clean(dirname)
+		}
+		if !found {
+			return fmt.Errorf("%s=%s names no directory", dirname, name)
+		}
+		g.names[filepath.Clean(dirname)] = name
+	}
+
+	if g.Merge || len(g.Files) != 0 {
+		what := "merged directories"
+		if !g.Merge {
+			what = "files given on their own"
+		}
+		if camelize(g.Name) == "" {
+			return fmt.Errorf("the name %q of the %s has no letters", g.Name, what)
+		}
+		for _, file := range reservedFiles {
+			if snakify(g.Name)+".go" == file {
+				return fmt.Errorf("the %s would be written to %s, which is reserved", what, file)
+			}
+		}
+		if g.Merge {
+			return nil
+		}
+	}
+
+	owners := make(map[string]string)
+	for _, file := range reservedFiles {
+		owners[file] = ""
+	}
+	// the files given on their own are owned by no directory either
+	named := make(map[string]bool)
+	if len(g.Files) != 0 {
+		named["Get"+camelize(g.Name)], named[snakify(g.Name)+".go"] = true, true
+	}
+	for _, dirname := range g.sources {
+		name := g.rootName(dirname)
+		ident := camelize(name)
+		if ident == "" {
+			return fmt.Errorf("%q has no letters to name its functions after, name it with -map %s=Name", name, dirname)
+		}
+		for _, key := range []string{"Get" + ident, snakify(name) + ".go"} {
+			if named[key] {
+				return fmt.Errorf("%q and the files given on their own would both get %s, rename it with -map %s=Name", dirname, key, dirname)
+			}
+			other, ok := owners[key]
+			switch {
+			case !ok:
+				owners[key] = dirname
+				continue
+			case other == "":
+				return fmt.Errorf("%q would be written to %s, which is reserved, rename it with -map %s=Name", dirname, key, dirname)
+			case filepath.Clean(other) == filepath.Clean(dirname):
+				return fmt.Errorf("%q is given twice", dirname)
+			}
+			return fmt.Errorf("%q and %q would both get %s, rename one with -map %s=Name", other, dirname, key, dirname)
+		}
+	}
+	return nil
+}
+
+// rootName returns the name of the root of the files of dirname, when they
+// aren't merged.
+func (g *generator) rootName(dirname string) string {
+	if name, ok := g.names[filepath.Clean(dirname)]; ok {
+		return name
+	}
+	if r, ok := g.remotes[dirname]; ok {
+		return r.name
+	}
+	if isArchive(dirname) {
+		return namePrefix(archiveDir(dirname))
+	}
+	return namePrefix(dirname)
+}
+
+// root is a set of files sharing accessors in the package, named after name.
+type root struct {
+	name     string
+	dirnames []string
+	entries  []entry
+	// emptyDirs are the names of the empty directories, with EmptyDirs
+	emptyDirs []string
+}
+
+// snapshotRoots snapshots the directories, each in its own root unless they
+// are merged, and the files given on their own, in a root of their own unless
+// they are merged along with the directories.
+func (g *generator) snapshotRoots(ctx context.Context) ([]root, error) {
+	var roots []root
+	merged := root{name: g.Name}
+	owners := make(map[string]string)
+	for _, dirname := range g.sources {
+
+		g.emptyDirs = nil
+		entries, err := g.snapshot(ctx, dirname)
+		if _, ok := err.(*Interrupted); ok {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot %q: %v", dirname, err)
+		}
+		if !g.Merge {
+			roots = append(roots, root{name: g.rootName(dirname), dirnames: []string{dirname}, entries: entries, emptyDirs: g.emptyDirs})
+			continue
+		}
+		merged.emptyDirs = append(merged.emptyDirs, g.emptyDirs...)
+		for _, e := range entries {
+			if other, ok := owners[e.Name]; ok {
+				return nil, fmt.Errorf("%q is found in both %q and %q", e.Name, other, dirname)
+			}
+			owners[e.Name] = dirname
+		}
+		merged.entries = append(merged.entries, entries...)
+		merged.dirnames = append(merged.dirnames, dirname)
+
+	}
+	if len(g.Files) != 0 {
+		entries, err := g.snapshotFiles(ctx, g.Files)
+		if _, ok := err.(*Interrupted); ok {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot the files: %v", err)
+		}
+		if !g.Merge {
+			roots = append(roots, root{name: g.Name, dirnames: g.Files, entries: entries})
+		} else {
+			for _, e := range entries {
+				if other, ok := owners[e.Name]; ok {
+					return nil, fmt.Errorf("%q is found in both %q and the files", e.Name, other)
+				}
+			}
+			merged.entries = append(merged.entries, entries...)
+			merged.dirnames = append(merged.dirnames, g.Files...)
+		}
+	}
+	if g.Merge {
+		sort.Sort(byEntryName(merged.entries))
+		roots = append(roots, merged)
+	}
+	return roots, nil
+}
+
+// driveLetters are the letters naming the volumes on Windows.
+const driveLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// namePrefix returns the prefix of the names of the files of dirname, its
+// clean and slash separated path, relative to where gostatic runs. Names are
+// the same on every platform, backslashes and volume names being read like
+// on Windows. They never go up with .., which is dropped, or start from the
+// root of the file system, which would depend on the machine: an absolute
+// path only keeps its base name.
+func namePrefix(dirname string) string {
+	name := strings.Replace(dirname, `\`, "/", -1)
+	switch {
+	case strings.HasPrefix(name, "//"):
+		// a UNC path, //server/share/dir
+		parts := strings.SplitN(name[2:], "/", 3)
+		name = "/"
+		if len(parts) == 3 {
+			name += parts[2]
+		}
+	case len(name) >= 2 && name[1] == ':' && strings.ContainsRune(driveLetters, rune(name[0])):
+		// a drive letter, C:\dir or C:dir
+		name = name[2:]
+	}
+	name = path.Clean(name)
+	if strings.HasPrefix(name, "/") {
+		if name = path.Base(name); name == "/" {
+			return "."
+		}
+		return name
+	}
+	for name == ".." || strings.HasPrefix(name, "../") {
+		name = strings.TrimPrefix(name[2:], "/")
+	}
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// entry is the encoded content of a file, as it is written in the generated
+// code. The content itself is spooled until then.
+type entry struct {
+	Name       string
+	Size       int
+	Mode       os.FileMode
+	ModTime    int64
+	Hash       string
+	Compressed bool
+	// ContentType is the MIME type of the file, from its extension or else
+	// from its content.
+	ContentType string
+	// Chunked is set when Literal holds a list of literals, the content
+	// being too large for a single one.
+	Chunked bool
+	// Block names the variable holding the content of the file, at Offset,
+	// with Solid.
+	Block  string
+	Offset int
+	// Brotli is set when the file has a Brotli variant, held by
+	// BrotliLiteral, which is chunked like Literal if BrotliChunked.
+	Brotli        bool
+	BrotliChunked bool
+	// Shared names the variable holding the entry, when other entries have
+	// the same content.
+	Shared string
+	// Dup names the variable holding the entry with the same content, which
+	// is written in place of this one's.
+	Dup string
+	// Original is the name of the file before it was fingerprinted.
+	Original string
+	// Integrity is the Subresource Integrity value of the file, if it is a
+	// script or a style sheet and Integrity is set.
+	Integrity string
+	// FS names the embed.FS holding the file with the embed backend, at
+	// File.
+	FS   string
+	File string
+	// source is the path of the file, with the embed backend.
+	source string
+	// gunzipped is set when the file is a gzip stream embedded as the
+	// content it holds.
+	gunzipped bool
+
+	// stored is the size of the data stored for the file, once compressed.
+	stored  int
+	payload payload
+	// brotli holds the Brotli variant, of brotliSize bytes
+	brotli     payload
+	brotliSize int
+	// solid is the content of the file until it is put in a block, with
+	// Solid, and blockShare its share of the literal of the block
+	solid      []byte
+	blockShare int64
+	// packed and brotliPacked slice the pack holding the content and its
+	// Brotli variant, with the bundle backend
+	packed       string
+	brotliPacked string
+	// Pak and BrotliPak are where the content and its Brotli variant are
+	// found in the pak, with the pak backend
+	Pak       *span
+	BrotliPak *span
+}
+
+// Literal returns the encoded content of the file, read back from the
+// spool, or the slice of the pack holding it with the bundle backend.
+func (e entry) Literal() (string, error) {
+	if e.packed != "" {
+		return e.packed, nil
+	}
+	return e.payload.literal()
+}
+
+func (g *generator) entry(name string, fi os.FileInfo, data []byte, literal string, compressed, chunked bool) (entry, error) {
+	// a dry run only needs the size of the literal
+	p := payload{length: int64(len(literal))}
+	if !g.dryRun {
+		var err error
+		if p, err = g.spool.add(literal); err != nil {
+			return entry{}, fmt.Errorf("couldn't spool %q: %v", name, err)
+		}
+	}
+	sum := sha256.Sum256(data)
+	e := entry{
+		Name:        name,
+		Size:        len(data),
+		Mode:        fi.Mode().Perm(),
+		Hash:        hex.EncodeToString(sum[:]),
+		Compressed:  compressed,
+		ContentType: contentType(name, data),
+		Chunked:     chunked,
+		payload:     p,
+	}
+	if g.ModTime {
+		e.ModTime = fi.ModTime().UnixNano()
+	}
+	if g.Integrity && isSubresource(name) {
+		sum := sha512.Sum384(data)
+		e.Integrity = "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	}
+	if g.Fingerprint && !isPage(name) {
+		e.Original, e.Name = name, fingerprint(name, e.Hash)
+	}
+	return e, nil
+}
+
+// obfuscate returns the key of the file called name with Obfuscate, computed
+// by the generated code as well.
+func obfuscate(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:16])
+}
+
+// isPage tells if the file called name is an HTML page.
+func isPage(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
+
+// isSubresource tells if the file called name is a script or a style sheet,
+// which pages can check the integrity of.
+func isSubresource(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".js", ".mjs", ".css":
+		return true
+	}
+	return false
+}
+
+// fingerprintLen is the number of hex digits of the hash put in fingerprinted
+// names.
+const fingerprintLen = 8
+
+// fingerprint inserts the start of hash in name, before its extension.
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	if ext == path.Base(name) {
+		// a dot file, like .htaccess, has no extension to speak of
+		ext = ""
+	}
+	return name[:len(name)-len(ext)] + "." + hash[:fingerprintLen] + ext
+}
+
+// contentType returns the MIME type of the file called name, looking at its
+// extension, or at its content if the extension is unknown. It is resolved
+// when generating, so that the target system doesn't need to know the
+// extension.
+func contentType(name string, data []byte) string {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype
+	}
+	return http.DetectContentType(data)
+}
+
+type byEntryName []entry
+
+func (b byEntryName) Len() int           { return len(b) }
+func (b byEntryName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+func (b byEntryName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// compressible tells if the file should be compressed, which is not the case
+// of files that are typically compressed already.
+func (g *generator) compressible(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return true
+	}
+	for _, raw := range g.NoCompressExt {
+		if strings.ToLower(strings.TrimSpace(raw)) == ext {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot returns the entries of the files to embed from a directory, an
+// archive or a remote source, sorted by name.
+func (g *generator) snapshot(ctx context.Context, dirname string) ([]entry, error) {
+	if r, ok := g.remotes[dirname]; ok {
+		local, err := g.fetch(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		if isArchive(local) {
+			return g.snapshotArchive(ctx, local, r.name)
+		}
+		return g.snapshotDir(ctx, local, r.name)
+	}
+	if isArchive(dirname) {
+		return g.snapshotArchive(ctx, dirname, archiveDir(dirname))
+	}
+	return g.snapshotDir(ctx, dirname, dirname)
+}
+
+// snapshotDir walks a directory and returns the entries of the files to
+// embed, sorted by name, which are named after the directory as.
+func (g *generator) snapshotDir(ctx context.Context, dirname, as string) ([]entry, error) {
+
+	// the walk only lists the files, which are read and compressed by the
+	// workers
+	var files []input
+	renamed := make(map[string]string)
+
+	var ignore ignorer
+	if !g.NoIgnore {
+		var err error
+		if ignore, err = loadIgnorer(dirname); err != nil {
+			return nil, err
+		}
+	}
+
+	err := walk(dirname, g.Symlinks == "follow", func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return g.failed(name, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return &Interrupted{File: name, Err: err}
+		}
+
+		rel, err := filepath.Rel(dirname, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel != "." && (g.exclude.match(rel) || ignore.ignored(rel, fi.IsDir())) {
+			reason := "excluded"
+			if !g.exclude.match(rel) {
+				reason = "ignored"
+			}
+			g.filter(name, fi.IsDir(), reason)
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			// the symlink isn't followed
+			if g.Symlinks == "error" {
+				return fmt.Errorf("%q is a symlink, follow or skip symlinks", name)
+			}
+			g.logf("Skipped symlink %q", name)
+			g.report.filter(name, false, "symlink")
+			return nil
+		}
+		if fi.IsDir() {
+			if g.EmptyDirs && rel != "." && isEmptyDir(name) {
+				g.emptyDir(path.Join(namePrefix(as), rel))
+			}
+			return nil
+		}
+		if len(g.include) != 0 && !g.include.match(rel) {
+			g.filter(name, false, "not included")
+			return nil
+		}
+		if err := g.budget(name, fi.Size()); err != nil {
+			return err
+		}
+
+		in, err := g.input(name, path.Join(namePrefix(as), rel), fi, renamed)
+		if err != nil {
+			return err
+		}
+		files = append(files, in)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g.read(ctx, dirname, files)
+}
+
+// isEmptyDir tells if the directory called dirname holds nothing.
+func isEmptyDir(dirname string) bool {
+	dir, err := os.Open(dirname)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = dir.Close() }()
+	_, err = dir.Readdirnames(1)
+	return err == io.EOF
+}
+
+// emptyDir records the empty directory found at rel, named like the files
+// would be.
+func (g *generator) emptyDir(rel string) {
+	name := g.renamer.rename(rel)
+	if name == "" || name == "." {
+		return
+	}
+	if g.Normalize != "none" {
+		name = norm.NFC.String(name)
+	}
+	g.emptyDirs = append(g.emptyDirs, name)
+}
+
+// snapshotFiles returns the entries of the files given on their own, sorted
+// by name.
+func (g *generator) snapshotFiles(ctx context.Context, filenames []string) ([]entry, error) {
+	var files []input
+	renamed := make(map[string]string)
+	for _, name := range filenames {
+		if err := ctx.Err(); err != nil {
+			return nil, &Interrupted{File: name, Err: err}
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			if err := g.failed(name, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if fi.IsDir() {
+			// like those listed by find
+			g.filter(name, true, "directory")
+			continue
+		}
+		if err := g.budget(name, fi.Size()); err != nil {
+			return nil, err
+		}
+		in, err := g.input(name, namePrefix(name), fi, renamed)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, in)
+	}
+	return g.read(ctx, g.Name, files)
+}
+
+// input is a file to read and embed as key.
+type input struct {
+	name string
+	key  string
+	fi   os.FileInfo
+	// read reads the file found in an archive
+	read func() ([]byte, error)
+}
+
+// readFile reads the content of the file.
+func (in input) readFile() ([]byte, error) {
+	if in.read != nil {
+		return in.read()
+	}
+	return ioutil.ReadFile(in.name)
+}
+
+// input returns the input of the file called name, found at the slash
+// separated path rel, making sure that no other file in renamed, keyed by
+// the names they are embedded as, is embedded as the same name.
+func (g *generator) input(name, rel string, fi os.FileInfo, renamed map[string]string) (input, error) {
+	key := g.renamer.rename(rel)
+	if key == "" {
+		return input{}, fmt.Errorf("%q is renamed to an empty name", name)
+	}
+	if !fs.ValidPath(key) {
+		return input{}, fmt.Errorf("%q is renamed to %q, which isn't a clean relative name", name, key)
+	}
+	if g.gunzipped(name) {
+		key = strings.TrimSuffix(key, ".gz")
+	}
+	if g.Normalize != "none" {
+		key = norm.NFC.String(key)
+	}
+	if other, ok := renamed[key]; ok {
+		return input{}, fmt.Errorf("%q and %q are both renamed to %q", other, name, key)
+	}
+	renamed[key] = name
+	return input{name: name, key: key, fi: fi}, nil
+}
+
+// read reads and compresses the files on the workers, and returns their
+// entries sorted by name. The progress bar, if any, is labeled after label.
+func (g *generator) read(ctx context.Context, label string, files []input) ([]entry, error) {
+	if g.Progress != nil && len(files) > 0 && len(files) >= g.ProgressMin {
+		var size int64
+		for _, f := range files {
+			size += f.fi.Size()
+		}
+		g.progress = newProgress(g.Progress, label, len(files), size)
+		defer func() {
+			g.progress.done()
+			g.progress = nil
+		}()
+	}
+
+	// work is canceled when a file fails, and ctx when the generation is
+	// interrupted
+	work, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// each worker fills the entries of the files it picks, so that their
+	// order doesn't depend on the scheduling
+	entries := make([]entry, len(files))
+	errs := make([]error, len(files))
+	skipped := make([]bool, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < g.jobs && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if ctx.Err() != nil {
+					continue
+				}
+				entries[i], errs[i] = g.encode(ctx, files[i])
+				if g.progress != nil {
+					g.progress.add(files[i].fi.Size())
+				}
+				if err := ctx.Err(); err != nil {
+					errs[i] = &Interrupted{File: files[i].name, Err: err}
+					continue
+				}
+				if errs[i] == nil {
+					continue
+				}
+				if errs[i] = g.failed(files[i].name, errs[i]); errs[i] != nil {
+					cancel()
+				} else {
+					skipped[i] = true
+				}
+			}
+		}()
+	}
+feed:
+	for i := range files {
+		select {
+		case next <- i:
+		case <-work.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		// tell which file was being compressed
+		for _, err := range errs {
+			if _, ok := err.(*Interrupted); ok {
+				return nil, err
+			}
+		}
+		return nil, &Interrupted{Err: err}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	read := entries[:0]
+	for i, e := range entries {
+		if !skipped[i] {
+			read = append(read, e)
+		}
+	}
+	entries = read
+	// the walk is in lexical order already, but the generated code must not
+	// depend on it
+	sort.Sort(byEntryName(entries))
+
+	return entries, nil
+}
+
+// encode reads, compresses and encodes the file of in, to be embedded as its
+// key. The commands it runs are killed when ctx is done.
+func (g *generator) encode(ctx context.Context, in input) (entry, error) {
+	name, key, fi := in.name, in.key, in.fi
+	data, err := in.readFile()
+	if err != nil {
+		return entry{}, err
+	}
+	// gz is the gzip stream of a file embedded as the content it holds,
+	// stored as it is with the gzip codec, unless that content is changed
+	var gz []byte
+	plain := name
+	if g.gunzipped(name) {
+		gz, plain = data, strings.TrimSuffix(name, ".gz")
+		if data, err = codecs["gzip"].decompress(gz); err != nil {
+			return entry{}, fmt.Errorf("couldn't gunzip %q: %v", name, err)
+		}
+	}
+	content := data
+	if data, err = g.pipe(ctx, name, key, data); err != nil {
+		return entry{}, err
+	}
+	compress := g.codec.compresses() && g.compressible(plain)
+	brotli := g.Brotli && g.compressible(plain)
+	solid := compress && g.solid(len(data))
+	var cacheKey string
+	if g.cache != nil && !g.hashing && !g.embedding && !solid {
+		cacheKey = g.cache.key(data, fmt.Sprintf("%s %d %t %t %t", g.codec.Name, g.level, compress, g.minifier != nil, brotli))
+		if c, ok := g.cache.get(cacheKey); ok {
+			return g.cachedEntry(name, key, fi, c, plain != name)
+		}
+	}
+	if g.minifier != nil {
+		size := len(data)
+		if data, err = g.minify(plain, data); err != nil {
+			return entry{}, err
+		}
+		if len(data) != size {
+			g.vlogf("Minified %q from %s to %s", name, humanize.Bytes(uint64(size)), humanize.Bytes(uint64(len(data))))
+		}
+	}
+	if gz != nil && (g.codec.Name != "gzip" || !bytes.Equal(data, content)) {
+		gz = nil
+	}
+	if g.hashing || g.embedding {
+		if g.embedding {
+			g.filelogf("%s\t\t\t%q (embedded)", humanize.Bytes(uint64(len(data))), name)
+		}
+		e, err := g.entry(key, fi, data, "", false, false)
+		e.stored, e.source = len(data), name
+		return e, err
+	}
+
+	if solid {
+		// compressed along with other small files, once they are all read
+		g.filelogf("%s	->		%q (in a block)", humanize.Bytes(uint64(len(data))), name)
+		e, err := g.entry(key, fi, data, "", false, false)
+		e.solid, e.gunzipped = data, plain != name
+		if err == nil && brotli {
+			e.stored = len(data)
+			_, err = g.withBrotli(&e, name, data)
+		}
+		return e, err
+	}
+
+	if !compress {
+		literal, chunked := g.literal(data)
+
+		g.filelogf("%s\t->\t%s\t%q (uncompressed)",
+			humanize.Bytes(uint64(len(data))),
+			humanize.Bytes(uint64(len(literal))),
+			name)
+		e, err := g.entry(key, fi, data, literal, false, chunked)
+		e.stored, e.gunzipped = len(data), plain != name
+		var br []byte
+		if err == nil && brotli {
+			br, err = g.withBrotli(&e, name, data)
+		}
+		if err == nil && g.cache != nil {
+			g.cache.put(cacheKey, cached{Stored: data, Brotli: br})
+		}
+		return e, err
+	}
+
+	stored, asIs := gz, ""
+	if gz != nil {
+		asIs = " (stored as is)"
+	} else {
+		buf := bytes.NewBuffer(nil)
+		cw, err := g.codec.newWriter(buf, g.level)
+		if err != nil {
+			return entry{}, err
+		}
+
+		if _, err = cw.Write(data); err == nil {
+			err = cw.Close()
+		}
+		if err != nil {
+			return entry{}, fmt.Errorf("couldn't compress %q: %v", name, err)
+		}
+		stored = buf.Bytes()
+	}
+
+	literal, chunked := g.literal(stored)
+
+	g.filelogf("%s\t->\t%s\t%q%s",
+		humanize.Bytes(uint64(len(data))),
+		humanize.Bytes(uint64(len(literal))),
+		name, asIs)
+	e, err := g.entry(key, fi, data, literal, true, chunked)
+	e.stored, e.gunzipped = len(stored), plain != name
+	var br []byte
+	if err == nil && brotli {
+		br, err = g.withBrotli(&e, name, data)
+	}
+	if err == nil && g.cache != nil {
+		g.cache.put(cacheKey, cached{Stored: stored, Compressed: true, Brotli: br})
+	}
+	return e, err
+}
+
+// withBrotli adds a Brotli variant to the entry of the file called name,
+// holding data, if it is smaller than what is stored already. It returns the
+// variant, or nil.
+func (g *generator) withBrotli(e *entry, name string, data []byte) ([]byte, error) {
+	br, err := compressBrotli(data, e.stored)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't compress %q with Brotli: %v", name, err)
+	}
+	return br, g.addBrotli(e, name, br)
+}
+
+// gunzipped tells if the file called name is embedded as the content of its
+// gzip stream, with Gunzip.
+func (g *generator) gunzipped(name string) bool {
+	base := filepath.Base(name)
+	return g.Gunzip && strings.HasSuffix(base, ".gz") && base != ".gz"
+}
+
+// cachedEntry returns the entry of the file called name, whose stored content
+// was found in the cache, and which is gunzipped if it is embedded as the
+// content of its gzip stream.
+func (g *generator) cachedEntry(name, key string, fi os.FileInfo, c cached, gunzipped bool) (entry, error) {
+	data := c.Stored
+	if c.Compressed {
+		var err error
+		if data, err = g.codec.decompress(c.Stored); err != nil {
+			return entry{}, fmt.Errorf("couldn't decompress %q from the cache: %v", name, err)
+		}
+	}
+	literal, chunked := g.literal(c.Stored)
+
+	g.filelogf("%s\t->\t%s\t%q (cached)",
+		humanize.Bytes(uint64(len(data))),
+		humanize.Bytes(uint64(len(literal))),
+		name)
+	e, err := g.entry(key, fi, data, literal, c.Compressed, chunked)
+	e.stored, e.gunzipped = len(c.Stored), gunzipped
+	if err == nil {
+		err = g.addBrotli(&e, name, c.Brotli)
+	}
+	return e, err
+}
+
+// literal encodes the data stored for a file. Data larger than a chunk is
+// split, and written as a list of literals.
+func (g *generator) literal(stored []byte) (literal string, chunked bool) {
+	external := g.external(len(stored))
+	if g.aead != nil {
+		stored = g.seal(stored)
+	}
+	if g.bundling || external {
+		// the pack holds the data as is
+		return string(stored), false
+	}
+	if g.chunkSize <= 0 || len(stored) <= g.chunkSize {
+		return g.encoding.literal(stored), false
+	}
+	// the lines are indented relative to the first, the formatting puts
+	// them in place
+	buf := bytes.NewBuffer(nil)
+	if g.encoding.Name == "bytes" {
+		_, _ = buf.WriteString("[][]byte{")
+	} else {
+		_, _ = buf.WriteString("[]string{")
+	}
+	for len(stored) > 0 {
+		n := g.chunkSize
+		if n > len(stored) {
+			n = len(stored)
+		}
+		_, _ = buf.WriteString("\n\t")
+		_, _ = buf.WriteString(strings.Replace(g.encoding.literal(stored[:n]), "\n", "\n\t", -1))
+		_ = buf.WriteByte(',')
+		stored = stored[n:]
+	}
+	_, _ = buf.WriteString("\n}")
+	return buf.String(), true
+}
+
+// external tells if stored content of n bytes goes to the pak of its root,
+// with the pak backend, rather than in a Go literal.
+func (g *generator) external(n int) bool {
+	return g.paking && int64(n) >= g.InlineThreshold
+}
+
+// writeRoot writes the files holding the entries snapshot from dirnames, and
+// their accessors, which are named after name. The empty directories are
+// listed along with those holding the entries.
+func (g *generator) writeRoot(out generated, name string, dirnames []string, entries []entry, emptyDirs []string) error {
+
+	destfilename := snakify(name) + ".go"
+	destfunction := camelize(name)
+
+	funcs := []string{"Get" + destfunction, "Reader" + destfunction, "ReadFile" + destfunction, "MustGet" + destfunction, "List" + destfunction, "Names" + destfunction, "Stat" + destfunction, "Size" + destfunction, "TotalSize" + destfunction, "Hash" + destfunction, "Hashes" + destfunction, "ContentType" + destfunction, "ContentTypes" + destfunction, "ReadDir" + destfunction, "Walk" + destfunction, "Glob" + destfunction}
+	if g.Obfuscate {
+		// listing the files would tell their names
+		funcs = []string{"Get" + destfunction, "Reader" + destfunction, "ReadFile" + destfunction, "MustGet" + destfunction, "Stat" + destfunction, "Size" + destfunction, "TotalSize" + destfunction, "Hash" + destfunction, "ContentType" + destfunction}
+	}
+	if g.Fingerprint {
+		funcs = append(funcs, "Manifest"+destfunction)
+	}
+	if g.Precompressed {
+		funcs = append(funcs, "GetCompressed"+destfunction)
+	}
+	if g.Release {
+		funcs = append(funcs, "Release"+destfunction, "Purge"+destfunction)
+	}
+	if g.Integrity {
+		funcs = append(funcs, "Integrity"+destfunction)
+	}
+	if g.Extract != "none" {
+		if !g.Obfuscate {
+			funcs = append(funcs, "ExtractToDir"+destfunction)
+		}
+		funcs = append(funcs, "ExtractFile"+destfunction)
+	}
+	if g.HTTP {
+		funcs = append(funcs, "HTTP"+destfunction, "Handler"+destfunction, "SPAHandler"+destfunction)
+	}
+	if g.Overrides {
+		funcs = append(funcs, "OverrideHandler"+destfunction)
+	}
+	if !g.Obfuscate {
+		funcs = append(funcs, "FS"+destfunction)
+	}
+	var templates []templateFile
+	for _, e := range entries {
+		name := e.Name
+		if e.Original != "" {
+			name = e.Original
+		}
+		if g.templates.matchName(name) {
+			templates = append(templates, templateFile{Name: name, Asset: e.Name})
+		}
+	}
+	if len(templates) != 0 {
+		funcs = append(funcs, "Templates"+destfunction)
+	}
+	locales, err := g.localeFiles(entries)
+	if err != nil {
+		return err
+	}
+	if len(locales) != 0 {
+		funcs = append(funcs, "Locales"+destfunction, "Locale"+destfunction)
+	}
+	savedfilename := filepath.Join(g.Output, snakify(name)+g.suffix+".go")
+	if g.Out != "" {
+		savedfilename = g.Out
+	}
+	usage := "usable with " + enumerate(funcs)
+	if g.Template != "" {
+		// the accessors are up to the template
+		usage = fmt.Sprintf("from template %q", g.Template)
+	}
+	if g.checking {
+		g.logf("checking %q, %s", filepath.Base(savedfilename), usage)
+	} else {
+		g.logf("saving to %q, %s", savedfilename, usage)
+	}
+
+	// the directories are written relative to the package, for the code
+	// not to depend on where it is checked out
+	pkgdir, err := filepath.Abs(g.Output)
+	if err != nil {
+		return err
+	}
+	var devroots []devRoot
+	for _, dirname := range dirnames {
+		devdir, err := filepath.Abs(dirname)
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(pkgdir, devdir); err == nil {
+			devdir = filepath.ToSlash(rel)
+		}
+		devroots = append(devroots, devRoot{
+			Prefix: namePrefix(dirname),
+			Dir:    devdir,
+		})
+	}
+
+	if g.embedding {
+		if err := g.embed(name, dirnames, entries); err != nil {
+			return err
+		}
+	} else {
+		g.dedup(entries, "shared"+destfunction)
+	}
+	blocks, err := g.solidify(entries, "block"+destfunction)
+	if err != nil {
+		return err
+	}
+	if len(blocks) != 0 {
+		g.solidifying = true
+	}
+	var pack, pak *pack
+	if g.bundling {
+		pack = g.bundle(out, name, entries, blocks)
+	}
+	if g.paking {
+		if pak, err = g.pak(out, name, entries, blocks); err != nil {
+			return err
+		}
+	}
+	g.report.add(destfunction, entries)
+	if g.Manifest {
+		for _, e := range entries {
+			g.manifest = append(g.manifest, manifestLine(e))
+		}
+	}
+	var folded []foldedName
+	if g.IgnoreCase {
+		if folded, err = foldNames(entries, g.Obfuscate); err != nil {
+			return err
+		}
+	}
+	var dirs []dir
+	if g.Obfuscate {
+		// the names are only known to the code looking them up
+		for i := range entries {
+			entries[i].Name = obfuscate(entries[i].Name)
+		}
+	} else {
+		dirs = tree(entries, emptyDirs)
+	}
+	var signed *signedManifest
+	if g.SignKey != nil {
+		signed = g.sign(entries)
+	}
+	parts := g.split(entries, "assets"+destfunction)
+
+	data := rootData{
+		PkgName:  g.PkgName,
+		RootName: destfunction,
+		Codec:    g.codec.Name,
+		Encoding: g.encoding.Name,
+		Entries:  entries,
+		Parts:    parts,
+		Split:    len(parts) > 1,
+		Blocks:   blocks,
+		Pack:     pack,
+		Pak:      pak,
+		HTTP:     g.HTTP,
+		IOFS:     g.IOFS,
+		Lazy:     g.Lazy,
+		Dev:      g.Dev,
+		DevRoots: devroots,
+		Renamer:  g.renamer,
+		Tree:     dirs,
+
+		Fingerprint: g.Fingerprint,
+		Integrity:   g.Integrity,
+		Extract:     g.Extract != "none",
+		Overrides:   g.Overrides,
+		Release:     g.Release,
+		Embed:       g.embedding,
+
+		FollowSymlinks: g.Symlinks == "follow",
+		Gunzip:         g.Gunzip,
+		Precompressed:  g.Precompressed,
+
+		Templates:     templates,
+		HTMLTemplates: g.HTMLTemplates,
+		Locales:       locales,
+		DefaultLocale: strings.ToLower(g.DefaultLocale),
+		Obfuscate:     g.Obfuscate,
+		Signed:        signed,
+		Folded:        folded,
+		NFCLookups:    g.Normalize == "nfc-lookups",
+	}
+
+	out.executeSpooled(destfilename, g.filetempl, data, g.spool)
+	if len(parts) > 1 {
+		g.splitting = true
+		for i, p := range parts {
+			out.executeSpooled(fmt.Sprintf("%s_%03d.go", snakify(name), i+1), g.filetempl.Lookup("part"), struct {
+				PkgName string
+				Dev     bool
+				Part    part
+			}{
+				PkgName: g.PkgName,
+				Dev:     g.Dev,
+				Part:    p,
+			}, g.spool)
+		}
+	}
+	if g.Dev {
+		out.executeSpooled(snakify(name)+"_embed.go", g.filetempl.Lookup("embed"), data, g.spool)
+		out.execute(snakify(name)+"_dev.go", g.filetempl.Lookup("dev"), data)
+	}
+	if g.IOFS {
+		out.execute(snakify(name)+"_fs_test.go", fstesttempl, data)
+	}
+	return nil
+}
+
+// rootData is what the file template of a root is executed with. It is
+// documented in the README for custom templates, keep them in sync.
+type rootData struct {
+	PkgName string
+	// RootName names the accessors, like Static for GetStatic.
+	RootName string
+	// Codec and Encoding name how the content of the entries is stored.
+	Codec    string
+	Encoding string
+	Entries  []entry
+	// Parts share the entries between the files of the root, there is a
+	// single one unless Split.
+	Parts []part
+	Split bool
+	// Blocks hold the content of the small files, with Solid.
+	Blocks []block
+	// Pack holds the content of the files with the bundle backend, and Pak
+	// with the pak backend.
+	Pack *pack
+	Pak  *pack
+	HTTP bool
+	IOFS bool
+	Lazy bool
+	Dev  bool
+	// DevRoots are the directories read in dev builds.
+	DevRoots []devRoot
+	Renamer  renamer
+	Tree     []dir
+
+	Fingerprint bool
+	Integrity   bool
+	// Extract is set when ExtractToDirX and ExtractFileX are generated.
+	Extract bool
+	// Overrides is set when OverrideHandlerX is generated.
+	Overrides bool
+	// Release is set when ReleaseX and PurgeX are generated.
+	Release bool
+	// Embed is set with the embed backend.
+	Embed bool
+	// FollowSymlinks is set when the symlinks are followed.
+	FollowSymlinks bool
+	// Gunzip is set when the gzip streams are embedded as their content.
+	Gunzip bool
+	// Precompressed is set when the gzip streams are kept, for
+	// GetCompressedX.
+	Precompressed bool
+
+	// Templates are the files parsed by TemplatesX, parsed with
+	// html/template if HTMLTemplates.
+	Templates     []templateFile
+	HTMLTemplates bool
+	// Locales are the catalogs found with Locales, and DefaultLocale the
+	// tag falling back on, in lower case.
+	Locales       []localeFile
+	DefaultLocale string
+	// Obfuscate is set when the names of the entries are hashes, which
+	// lookups hash the names they are given to find.
+	Obfuscate bool
+	// Signed is the manifest of the entries with SignKey.
+	Signed *signedManifest
+	// Folded maps the names of the entries in lower case to them, for
+	// lookups ignoring case, with IgnoreCase.
+	Folded []foldedName
+	// NFCLookups is set when the names looked up are normalized to NFC,
+	// like the names of the entries.
+	NFCLookups bool
+}
+
+// foldedName is the name of an entry, and the same in lower case, or their
+// keys with Obfuscate.
+type foldedName struct {
+	Folded string
+	Name   string
+}
+
+// foldNames returns the names of the entries in lower case, sorted. Two names
+// only differing by case are an error, the lookups couldn't tell them apart.
+func foldNames(entries []entry, obfuscated bool) ([]foldedName, error) {
+	names := make(map[string]string)
+	folded := make([]foldedName, 0, len(entries))
+	for _, e := range entries {
+		lower := strings.ToLower(e.Name)
+		if other, ok := names[lower]; ok {
+			return nil, fmt.Errorf("%q and %q only differ by case, which lookups ignoring it can't tell apart", other, e.Name)
+		}
+		names[lower] = e.Name
+		if obfuscated {
+			folded = append(folded, foldedName{Folded: obfuscate(lower), Name: obfuscate(e.Name)})
+		} else {
+			folded = append(folded, foldedName{Folded: lower, Name: e.Name})
+		}
+	}
+	sort.Slice(folded, func(i, j int) bool { return folded[i].Folded < folded[j].Folded })
+	return folded, nil
+}
+
+// templateFile is a file parsed by TemplatesX, into a template called Name,
+// the name of the file before it was fingerprinted, if it was. It is found
+// with the name Asset.
+type templateFile struct {
+	Name  string
+	Asset string
+}
+
+// devRoot is a directory read in dev builds, holding the files whose names
+// start with Prefix. Dir is slash separated and relative to the package,
+// unless it can't be, like when it is on another volume.
+type devRoot struct {
+	Prefix string
+	Dir    string
+}
+
+// dir is a directory derived from the names of the entries, "." being the
+// root. Children are the names of its files and directories, sorted.
+type dir struct {
+	Name     string
+	Children []string
+}
+
+// tree returns the directories holding the entries, and the empty ones,
+// sorted by name.
+func tree(entries []entry, emptyDirs []string) []dir {
+	children := map[string]map[string]bool{".": {}}
+	add := func(name string) {
+		for ; name != "." && name != "/"; name = path.Dir(name) {
+			parent := path.Dir(name)
+			if children[parent] == nil {
+				children[parent] = make(map[string]bool)
+			}
+			children[parent][path.Base(name)] = true
+		}
+	}
+	for _, e := range entries {
+		add(e.Name)
+	}
+	for _, name := range emptyDirs {
+		if children[name] == nil {
+			children[name] = make(map[string]bool)
+		}
+		add(name)
+	}
+	dirs := make([]dir, 0, len(children))
+	for name, names := range children {
+		d := dir{Name: name}
+		for child := range names {
+			d.Children = append(d.Children, child)
+		}
+		sort.Strings(d.Children)
+		dirs = append(dirs, d)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name < dirs[j].Name })
+	return dirs
+}
+
+// dedup finds the entries with the same content, and has them refer to the
+// first of them, which is held in a variable named after name.
+func (g *generator) dedup(entries []entry, name string) {
+	type content struct {
+		hash       string
+		compressed bool
+	}
+	first := make(map[content]int)
+	shared, dups, saved := 0, 0, int64(0)
+	for i, e := range entries {
+		c := content{hash: e.Hash, compressed: e.Compressed}
+		j, ok := first[c]
+		if !ok {
+			first[c] = i
+			continue
+		}
+		if entries[j].Shared == "" {
+			shared++
+			entries[j].Shared = fmt.Sprintf("%s%d", name, shared)
+		}
+		entries[i].Dup = entries[j].Shared
+		dups++
+		saved += entries[i].payload.length
+	}
+	if dups != 0 {
+		g.logf("%d files have the same content as others, saving %s",
+			dups, humanize.Bytes(uint64(saved)))
+	}
+}
+
+// part is a share of the entries of a root, held in a map called Var.
+type part struct {
+	Var     string
+	Entries []entry
+}
+
+// split shares the entries in parts of about g.splitSize bytes of generated
+// code, each held in a map named after name. It returns a single part, called
+// name, if the entries are small enough.
+func (g *generator) split(entries []entry, name string) []part {
+	if g.splitSize <= 0 {
+		return []part{{Var: name, Entries: entries}}
+	}
+	var parts []part
+	size := 0
+	for _, e := range entries {
+		// the literal makes most of an entry, along with a line of fields
+		n := 2*len(e.Name) + 128
+		if e.Dup == "" && e.Pak == nil {
+			n += int(e.payload.length)
+		}
+		if e.Dup == "" && e.BrotliPak == nil {
+			n += int(e.brotli.length)
+		}
+		if len(parts) == 0 || (size+n > g.splitSize && size != 0) {
+			parts = append(parts, part{})
+			size = 0
+		}
+		parts[len(parts)-1].Entries = append(parts[len(parts)-1].Entries, e)
+		size += n
+	}
+	if len(parts) <= 1 {
+		return []part{{Var: name, Entries: entries}}
+	}
+	for i := range parts {
+		parts[i].Var = fmt.Sprintf("%s%03d", name, i+1)
+	}
+	return parts
+}
+
+// writeSupportFile writes a file shared by all the directories of the
+// package.
+func (g *generator) writeSupportFile(out generated, filename string, templ *template.Template) {
+	out.execute(filename, templ, struct {
+		PkgName       string
+		CacheControl  string
+		HTTP          bool
+		Overrides     bool
+		IOFS          bool
+		Precompressed bool
+		Brotli        bool
+		Integrity     bool
+		Gunzip        bool
+		IgnoreCase    bool
+		Release       bool
+	}{
+		PkgName:       g.PkgName,
+		CacheControl:  g.CacheControl,
+		HTTP:          g.HTTP,
+		Overrides:     g.Overrides,
+		IOFS:          g.IOFS,
+		Precompressed: g.Precompressed,
+		Brotli:        g.Brotli,
+		Integrity:     g.Integrity,
+		Gunzip:        g.Gunzip,
+		IgnoreCase:    g.IgnoreCase,
+		Release:       g.Release,
+	})
+}
+
+// writeCommonFile writes the asset type used by all the directories of the
+// package.
+func (g *generator) writeCommonFile(out generated) {
+	out.execute("gostatic.go", commontempl, struct {
+		PkgName       string
+		Codec         codec
+		Encoding      encoding
+		Precompressed bool
+		Brotli        bool
+		Split         bool
+		Solid         bool
+		Integrity     bool
+		Embed         bool
+		Pak           bool
+		PakSumAt      int
+		Encrypt       bool
+		KeyEnv        string
+		Obfuscate     bool
+		Signed        bool
+		PublicKey     string
+		IgnoreCase    bool
+		NFCLookups    bool
+		Extract       string
+		Locales       bool
+		Release       bool
+		MemoryBudget  int64
+	}{
+		PkgName:       g.PkgName,
+		Codec:         g.codec,
+		Encoding:      g.encoding,
+		Precompressed: g.Precompressed,
+		Brotli:        g.Brotli,
+		Split:         g.splitting,
+		Solid:         g.solidifying,
+		Integrity:     g.Integrity,
+		Embed:         g.embedding,
+		Pak:           g.paking,
+		PakSumAt:      len(pakHeader),
+		Encrypt:       g.aead != nil,
+		KeyEnv:        g.KeyEnv,
+		Obfuscate:     g.Obfuscate,
+		Signed:        g.SignKey != nil,
+		PublicKey:     publicKey(g.SignKey),
+		IgnoreCase:    g.IgnoreCase,
+		NFCLookups:    g.Normalize == "nfc-lookups",
+		Extract:       g.Extract,
+		Locales:       len(g.Locales) != 0,
+		Release:       g.Release,
+		MemoryBudget:  g.MemoryBudget,
+	})
+}
+
+func enumerate(names []string) string {
+	if len(names) == 1 {
+		return "function " + names[0]
+	}
+	return "functions " + strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+func snakify(input string) string {
+	out := bytes.NewBuffer(nil)
+	lastWasSnake := true
+
+	for i, r := range []rune(input) {
+
+		switch {
+		case unicode.IsLetter(r):
+			_, _ = out.WriteRune(r)
+			lastWasSnake = false
+		case lastWasSnake:
+			// skip it
+		case i != len(input)-1:
+			_, _ = out.WriteRune('_')
+		}
+	}
+	return out.String()
+}
+
+func camelize(input string) string {
+	out := bytes.NewBuffer(nil)
+	needCamel := true
+	for _, r := range []rune(input) {
+
+		switch {
+		case unicode.IsLetter(r) && needCamel:
+			_, _ = out.WriteRune(unicode.ToUpper(r))
+			needCamel = false
+		case unicode.IsLetter(r) && !needCamel:
+			_, _ = out.WriteRune(r)
+		default:
+			needCamel = true
+		}
+	}
+	return out.String()
+}
+
+// writeDocFile writes the documentation of the package, telling how it is
+// generated and what its roots hold, in the order they were written.
+func (g *generator) writeDocFile(out generated) {
+	type sum struct {
+		files        int
+		size, stored int64
+	}
+	var roots []string
+	sums := make(map[string]*sum)
+	for _, f := range g.report.Files {
+		s, ok := sums[f.Root]
+		if !ok {
+			s = &sum{}
+			sums[f.Root] = s
+			roots = append(roots, f.Root)
+		}
+		s.files++
+		s.size += f.Size
+		if !f.Duplicate {
+			s.stored += f.CompressedSize
+		}
+	}
+	buf := bytes.NewBuffer(nil)
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	for _, root := range roots {
+		s := sums[root]
+		files := "files"
+		if s.files == 1 {
+			files = "file"
+		}
+		fmt.Fprintf(w, "%s\t%d %s\t%s\tstored in %s\n", root, s.files, files,
+			humanize.Bytes(uint64(s.size)), humanize.Bytes(uint64(s.stored)))
+	}
+	_ = w.Flush()
+	var lines []string
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line = strings.TrimRight(line, " \n"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	out.execute("doc.go", doctempl, struct {
+		PkgName  string
+		Command  string
+		Generate string
+		Roots    []string
+	}{
+		PkgName:  g.PkgName,
+		Command:  commentSafe(g.Command),
+		Generate: commentSafe(g.Generate),
+		Roots:    lines,
+	})
+}
@@ -0,0 +1,144 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFiles writes the files, keyed by their slash separated names, to dir.
+func writeFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// generate generates the package described by opts, failing the test if it
+// can't.
+func generate(t *testing.T, opts Options) {
+	t.Helper()
+	if err := Generate(context.Background(), opts); err != nil {
+		t.Fatalf("couldn't generate the package: %v", err)
+	}
+}
+
+// TestReproducible checks that the same files generate the same code, from
+// another checkout, with other modification times.
+func TestReproducible(t *testing.T) {
+	files := map[string][]byte{
+		"index.html":     []byte("<p>hello</p>"),
+		"css/app.css":    []byte("body { color: red }"),
+		"js/app.js":      []byte("console.log(1)"),
+		"img/logo.png":   {0x89, 'P', 'N', 'G'},
+		"data/empty.txt": {},
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	var packages []map[string][]byte
+	for _, modTime := range []time.Time{time.Unix(0, 0), time.Now()} {
+		dir := t.TempDir()
+		writeFiles(t, filepath.Join(dir, "assets"), files)
+		for name := range files {
+			filename := filepath.Join(dir, "assets", filepath.FromSlash(name))
+			if err := os.Chtimes(filename, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// the directories are given relative to the working directory,
+		// like they are in checkouts
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+		generate(t, Options{
+			Dirs:     []string{"assets"},
+			Output:   "staticfs",
+			HTTP:     true,
+			Brotli:   true,
+			IOFS:     true,
+			Dev:      true,
+			SelfTest: true,
+			Manifest: true,
+			Jobs:     4,
+		})
+		infos, err := ioutil.ReadDir("staticfs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg := make(map[string][]byte)
+		for _, fi := range infos {
+			if pkg[fi.Name()], err = ioutil.ReadFile(filepath.Join("staticfs", fi.Name())); err != nil {
+				t.Fatal(err)
+			}
+		}
+		packages = append(packages, pkg)
+	}
+
+	for filename, want := range packages[0] {
+		if got, ok := packages[1][filename]; !ok {
+			t.Errorf("%s isn't generated again", filename)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("%s differs: %s", filename, summarizeDiff(want, got))
+		}
+	}
+	for filename := range packages[1] {
+		if _, ok := packages[0][filename]; !ok {
+			t.Errorf("%s is only generated the second time", filename)
+		}
+	}
+}
+
+// TestNamePrefix checks that the directories are named alike on every
+// platform, relative to where gostatic runs.
+func TestNamePrefix(t *testing.T) {
+	tests := []struct {
+		dirname string
+		want    string
+	}{
+		{dirname: "", want: "."},
+		{dirname: ".", want: "."},
+		{dirname: "./", want: "."},
+		{dirname: "/", want: "."},
+		{dirname: "assets", want: "assets"},
+		{dirname: "./assets", want: "assets"},
+		{dirname: "assets/", want: "assets"},
+		{dirname: "assets//img", want: "assets/img"},
+		{dirname: "assets/../web", want: "web"},
+		{dirname: "..", want: "."},
+		{dirname: "../assets", want: "assets"},
+		{dirname: "../../web/dist", want: "web/dist"},
+		{dirname: "assets/../../web", want: "web"},
+		{dirname: "..assets", want: "..assets"},
+		{dirname: "/abs/assets", want: "assets"},
+		{dirname: "/abs/../assets/", want: "assets"},
+		{dirname: `.\assets\img`, want: "assets/img"},
+		{dirname: `assets/img\icons`, want: "assets/img/icons"},
+		{dirname: `..\assets`, want: "assets"},
+		{dirname: `C:\abs\assets`, want: "assets"},
+		{dirname: `c:/abs/assets`, want: "assets"},
+		{dirname: `C:assets`, want: "assets"},
+		{dirname: `C:..\assets`, want: "assets"},
+		{dirname: `C:\`, want: "."},
+		{dirname: `\\server\share\abs\assets`, want: "assets"},
+		{dirname: `\\server\share`, want: "."},
+	}
+	for _, tt := range tests {
+		if got := namePrefix(tt.dirname); got != tt.want {
+			t.Errorf("namePrefix(%q) = %q, want %q", tt.dirname, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package gen
+
+import (
+	"path"
+	"strings"
+)
+
+// globs is a list of patterns matched against slash separated paths. A
+// pattern follows the syntax of path.Match, with the addition of `**` which
+// matches any number of directories, including none.
+type globs []string
+
+// parseGlobs validates a list of patterns.
+func parseGlobs(list []string) (globs, error) {
+	var g globs
+	for _, pattern := range list {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		g = append(g, pattern)
+	}
+	return g, nil
+}
+
+// match tells if name matches any of the patterns.
+func (g globs) match(name string) bool {
+	for _, pattern := range g {
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchName is like match, except that the patterns without a slash are
+// matched against the last element of name, so that "*.tmpl" matches
+// "views/page.tmpl".
+func (g globs) matchName(name string) bool {
+	for _, pattern := range g {
+		if strings.Contains(pattern, "/") {
+			if matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/")) {
+				return true
+			}
+		} else if ok, _ := path.Match(pattern, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,103 @@
+package gen
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFiles are read from the root of each directory, in order. Rules of
+// the later files take precedence over the ones of the earlier files.
+var ignoreFiles = []string{".gitignore", ".gostaticignore"}
+
+// ignoreRule is a single line of an ignore file, in gitignore syntax.
+type ignoreRule struct {
+	pattern []string
+	negate  bool
+	dirOnly bool
+}
+
+// ignorer tells which files to skip according to a list of rules. The last
+// rule matching a file decides whether it is ignored.
+type ignorer []ignoreRule
+
+// loadIgnorer reads the ignore files found at the root of dirname. Missing
+// files are not an error.
+func loadIgnorer(dirname string) (ignorer, error) {
+	var ig ignorer
+	for _, filename := range ignoreFiles {
+		file, err := os.Open(filepath.Join(dirname, filename))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		rules, err := parseIgnore(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+		ig = append(ig, rules...)
+	}
+	return ig, nil
+}
+
+func parseIgnore(r io.Reader) (ignorer, error) {
+	var ig ignorer
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := strings.TrimRight(scan.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// patterns without a slash match at any depth, the others are
+		// relative to the root
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		rule.pattern = strings.Split(line, "/")
+		ig = append(ig, rule)
+	}
+	return ig, scan.Err()
+}
+
+// ignored tells if the file or directory at the slash separated path rel
+// should be skipped. Like git, the `.git` directory is always skipped, and
+// so is the `.gostaticignore` file.
+func (ig ignorer) ignored(rel string, dir bool) bool {
+	base := rel[strings.LastIndex(rel, "/")+1:]
+	if (dir && base == ".git") || (!dir && base == ".gostaticignore") {
+		return true
+	}
+
+	segments := strings.Split(rel, "/")
+	ignored := false
+	for _, rule := range ig {
+		if rule.dirOnly && !dir {
+			continue
+		}
+		if matchSegments(rule.pattern, segments) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
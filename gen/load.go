@@ -0,0 +1,724 @@
+package gen
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Asset is a file embedded in a generated package, as read back by Load.
+type Asset struct {
+	// Root names the accessors of the asset, like Static for GetStatic.
+	Root string
+	Name string
+	Size int64
+	Mode os.FileMode
+	// ModTime is the zero time if it wasn't recorded.
+	ModTime time.Time
+	// Hash is the hex encoded SHA-256 of the content, as recorded.
+	Hash string
+
+	compressed bool
+	values     [][]byte
+	// raw is set when the values are read from a pack, as they are stored
+	raw      bool
+	codec    codec
+	encoding encoding
+	// aead decrypts the stored content, if it is encrypted
+	aead cipher.AEAD
+	// file holds the content instead with the embed backend, relative to
+	// the package directory dir
+	file string
+	dir  string
+	// block holds the content instead at offset, with Solid
+	block  *Asset
+	offset int64
+}
+
+// Data decodes and decompresses the content of the asset.
+func (a Asset) Data() ([]byte, error) {
+	if a.file != "" {
+		return ioutil.ReadFile(filepath.Join(a.dir, filepath.FromSlash(a.file)))
+	}
+	if a.block != nil {
+		data, err := a.block.Data()
+		if err != nil {
+			return nil, err
+		}
+		if a.offset+a.Size > int64(len(data)) {
+			return nil, fmt.Errorf("%q is out of block %s", a.Name, a.block.Name)
+		}
+		return data[a.offset : a.offset+a.Size], nil
+	}
+	var stored []byte
+	for _, value := range a.values {
+		if a.raw {
+			stored = append(stored, value...)
+			continue
+		}
+		data, err := a.encoding.decode(value)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode %q: %v", a.Name, err)
+		}
+		stored = append(stored, data...)
+	}
+	if a.aead != nil {
+		var err error
+		if stored, err = unseal(a.aead, stored); err != nil {
+			return nil, fmt.Errorf("couldn't decrypt %q: %v", a.Name, err)
+		}
+	}
+	if !a.compressed {
+		return stored, nil
+	}
+	data, err := a.codec.decompress(stored)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decompress %q: %v", a.Name, err)
+	}
+	return data, nil
+}
+
+// intact tells if the content of the asset matches its recorded size and
+// hash.
+func (a Asset) intact() (bool, error) {
+	data, err := a.Data()
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	return int64(len(data)) == a.Size && hex.EncodeToString(sum[:]) == a.Hash, nil
+}
+
+// storedWith finds the codec and the encoding in the doc of the asset type.
+var storedWith = regexp.MustCompile(`stored with the (\w+) codec and the\s+(?://\s+)?(\w+) encoding`)
+
+// keyEnvConst finds the environment variable holding the key of the
+// encrypted assets.
+var keyEnvConst = regexp.MustCompile(`const keyEnv = "(\w+)"`)
+
+// IsPackage tells if path is the directory of a generated package, or the
+// single file written with Out.
+func IsPackage(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if fi.IsDir() {
+		// every package has its common file, suffixed if it was appended
+		// to another package
+		common := filepath.Join(path, "gostatic.go")
+		if isgen, err := isGenerated(common); err == nil && isgen {
+			return true
+		}
+		path = filepath.Join(path, "gostatic"+appendSuffix+".go")
+	} else if filepath.Ext(path) != ".go" {
+		return false
+	}
+	isgen, err := isGenerated(path)
+	return err == nil && isgen
+}
+
+// appendSuffix ends the names of the Go files of a package appended to
+// another, with Append.
+const appendSuffix = "_gostatic"
+
+// packageName returns the name of the package found in dir, which its Go
+// files declare, leaving out the tests and the files generated by gostatic.
+func packageName(dir string) (string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	name := ""
+	fset := token.NewFileSet()
+	for _, fi := range infos {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".go" || strings.HasSuffix(fi.Name(), "_test.go") {
+			continue
+		}
+		filename := filepath.Join(dir, fi.Name())
+		if isgen, err := isGenerated(filename); err != nil {
+			return "", err
+		} else if isgen {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filename, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		if name != "" && f.Name.Name != name {
+			return "", fmt.Errorf("%s holds the packages %s and %s", dir, name, f.Name.Name)
+		}
+		name = f.Name.Name
+	}
+	if name == "" {
+		return "", fmt.Errorf("%s holds no Go files", dir)
+	}
+	return name, nil
+}
+
+// Load reads the assets embedded in a generated package, found at path. It
+// is either the directory of the package, or the single file written with
+// Out. The assets are sorted by root and name.
+func Load(path string) ([]Asset, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	filenames, err := packageFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	l := loader{
+		maps:   make(map[string][]loadedEntry),
+		shared: make(map[string]*Asset),
+		blocks: make(map[string]*Asset),
+		joins:  make(map[string][]string),
+		packs:  make(map[string]string),
+	}
+	var codecName, encodingName, keyEnv string
+	fset := token.NewFileSet()
+	for _, filename := range filenames {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if m := storedWith.FindSubmatch(src); m != nil {
+			codecName, encodingName = string(m[1]), string(m[2])
+		}
+		if m := keyEnvConst.FindSubmatch(src); m != nil {
+			keyEnv = string(m[1])
+		}
+		// the comments tell the files embedded with the bundle backend
+		file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.file(file); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+	if codecName == "" {
+		return nil, fmt.Errorf("no package generated by gostatic found at %s", path)
+	}
+	c, err := lookupCodec(codecName)
+	if err != nil {
+		return nil, err
+	}
+	e, err := lookupEncoding(encodingName)
+	if err != nil {
+		return nil, err
+	}
+	var aead cipher.AEAD
+	if keyEnv != "" {
+		key, err := hex.DecodeString(strings.TrimSpace(os.Getenv(keyEnv)))
+		if err == nil && len(key) == 0 {
+			err = fmt.Errorf("not set")
+		}
+		if err == nil {
+			aead, err = newAEAD(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("the files are encrypted, couldn't read their key from $%s: %v", keyEnv, err)
+		}
+	}
+	dir := path
+	if !fi.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	return l.assets(c, e, aead, dir)
+}
+
+// packageFiles returns the Go files of the generated package found at path,
+// but the tests, or path itself if it is the single file written with Out.
+func packageFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var filenames []string
+	for _, fi := range infos {
+		name := fi.Name()
+		if fi.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		filename := filepath.Join(path, name)
+		if isgen, err := isGenerated(filename); err != nil {
+			return nil, err
+		} else if isgen {
+			filenames = append(filenames, filename)
+		}
+	}
+	return filenames, nil
+}
+
+// LoadEmptyDirs returns the names of the empty directories of a generated
+// package found at path, like Load, sorted. They are only kept with
+// EmptyDirs.
+func LoadEmptyDirs(path string) ([]string, error) {
+	filenames, err := packageFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	fset := token.NewFileSet()
+	for _, filename := range filenames {
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				// the directories of a root are listed in dirsX, with
+				// what each holds
+				vs := spec.(*ast.ValueSpec)
+				if len(vs.Names) != 1 || !strings.HasPrefix(vs.Names[0].Name, "dirs") || len(vs.Values) != 1 {
+					continue
+				}
+				lit, ok := vs.Values[0].(*ast.CompositeLit)
+				if !ok {
+					continue
+				}
+				for _, elt := range lit.Elts {
+					kv, ok := elt.(*ast.KeyValueExpr)
+					if !ok {
+						continue
+					}
+					key, ok := kv.Key.(*ast.BasicLit)
+					children, isList := kv.Value.(*ast.CompositeLit)
+					if !ok || !isList || len(children.Elts) != 0 {
+						continue
+					}
+					if name, err := strconv.Unquote(key.Value); err == nil && name != "." {
+						dirs = append(dirs, name)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// loadedEntry is an entry of a map of assets, which is either an asset or a
+// reference to a shared one.
+type loadedEntry struct {
+	asset *Asset
+	ref   string
+}
+
+// loader gathers the variables holding assets in a generated package.
+type loader struct {
+	// maps are the maps of assets, by variable name
+	maps map[string][]loadedEntry
+	// shared are the assets held in their own variable
+	shared map[string]*Asset
+	// blocks are the blocks of small assets, read as compressed assets
+	blocks map[string]*Asset
+	// joins are the maps put together from several others
+	joins map[string][]string
+	// dups are the assets with the content of a shared one, and members
+	// the assets with their content in a block
+	dups    []dup
+	members []dup
+	// packs are the files embedded with the bundle backend or read with
+	// the pak backend, by variable name, and slices the assets with their
+	// content in them
+	packs  map[string]string
+	slices []slice
+}
+
+type dup struct {
+	asset *Asset
+	ref   string
+}
+
+// slice is the content of an asset, found from lo to hi in the pack held by
+// ref.
+type slice struct {
+	asset  *Asset
+	ref    string
+	lo, hi int64
+}
+
+func (l *loader) file(file *ast.File) error {
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if len(vs.Names) == 1 && len(vs.Values) == 0 && gd.Doc != nil {
+				if file, ok := embedded(gd.Doc); ok {
+					l.packs[vs.Names[0].Name] = file
+				}
+				continue
+			}
+			if len(vs.Names) != 1 || len(vs.Values) != 1 {
+				continue
+			}
+			name := vs.Names[0].Name
+			switch value := vs.Values[0].(type) {
+			case *ast.CompositeLit:
+				if !isAssetMap(value.Type) {
+					continue
+				}
+				entries, err := l.mapLit(value)
+				if err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+				l.maps[name] = entries
+			case *ast.UnaryExpr:
+				lit, ok := value.X.(*ast.CompositeLit)
+				if !ok || value.Op != token.AND {
+					continue
+				}
+				if id, ok := lit.Type.(*ast.Ident); ok && id.Name == "pak" {
+					if file, ok := pakFile(lit); ok {
+						l.packs[name] = file
+					}
+					continue
+				}
+				a, err := l.assetLit(lit)
+				if err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+				if id, ok := lit.Type.(*ast.Ident); ok && id.Name == "block" {
+					a.Name, a.compressed = name, true
+					l.blocks[name] = a
+					continue
+				}
+				l.shared[name] = a
+			case *ast.CallExpr:
+				if fun, ok := value.Fun.(*ast.Ident); !ok || fun.Name != "joinAssets" {
+					continue
+				}
+				for _, arg := range value.Args {
+					if id, ok := arg.(*ast.Ident); ok {
+						l.joins[name] = append(l.joins[name], id.Name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// embedded returns the file embedded in the variable documented by doc, if
+// any.
+func embedded(doc *ast.CommentGroup) (string, bool) {
+	for _, c := range doc.List {
+		if pattern := strings.TrimPrefix(c.Text, "//go:embed "); pattern != c.Text {
+			file, err := strconv.Unquote(strings.TrimSpace(pattern))
+			return file, err == nil
+		}
+	}
+	return "", false
+}
+
+// sliceLit records that the content of a is sliced from a pack.
+func (l *loader) sliceLit(a *Asset, sl *ast.SliceExpr) error {
+	id, ok := sl.X.(*ast.Ident)
+	if !ok || sl.Low == nil || sl.High == nil {
+		return fmt.Errorf("unexpected slice")
+	}
+	lo, err := intLit(sl.Low)
+	if err != nil {
+		return err
+	}
+	hi, err := intLit(sl.High)
+	if err != nil {
+		return err
+	}
+	l.slices = append(l.slices, slice{asset: a, ref: id.Name, lo: lo, hi: hi})
+	return nil
+}
+
+// pakFile returns the file of a pak literal.
+func pakFile(lit *ast.CompositeLit) (string, bool) {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "file" {
+				file, err := stringLit(kv.Value)
+				return file, err == nil
+			}
+		}
+	}
+	return "", false
+}
+
+// spanLit records that the content of a is read from a pak.
+func (l *loader) spanLit(a *Asset, expr ast.Expr) error {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok || len(lit.Elts) != 3 {
+		return fmt.Errorf("unexpected span")
+	}
+	id, ok := lit.Elts[0].(*ast.Ident)
+	if !ok {
+		return fmt.Errorf("unexpected span")
+	}
+	at, err := intLit(lit.Elts[1])
+	if err != nil {
+		return err
+	}
+	n, err := intLit(lit.Elts[2])
+	if err != nil {
+		return err
+	}
+	l.slices = append(l.slices, slice{asset: a, ref: id.Name, lo: at, hi: at + n})
+	return nil
+}
+
+// isAssetMap tells if typ is map[string]*asset.
+func isAssetMap(typ ast.Expr) bool {
+	mt, ok := typ.(*ast.MapType)
+	if !ok {
+		return false
+	}
+	star, ok := mt.Value.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	id, ok := star.X.(*ast.Ident)
+	return ok && id.Name == "asset"
+}
+
+func (l *loader) mapLit(lit *ast.CompositeLit) ([]loadedEntry, error) {
+	var entries []loadedEntry
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return nil, fmt.Errorf("unexpected map element")
+		}
+		switch value := kv.Value.(type) {
+		case *ast.Ident:
+			entries = append(entries, loadedEntry{ref: value.Name})
+		case *ast.CompositeLit:
+			a, err := l.assetLit(value)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, loadedEntry{asset: a})
+		default:
+			return nil, fmt.Errorf("unexpected map value")
+		}
+	}
+	return entries, nil
+}
+
+func (l *loader) assetLit(lit *ast.CompositeLit) (*Asset, error) {
+	a := &Asset{}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return nil, fmt.Errorf("unexpected asset field")
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unexpected asset field")
+		}
+		var err error
+		switch key.Name {
+		case "name":
+			a.Name, err = stringLit(kv.Value)
+		case "hash":
+			a.Hash, err = stringLit(kv.Value)
+		case "size":
+			a.Size, err = intLit(kv.Value)
+		case "mode":
+			var mode int64
+			mode, err = intLit(kv.Value)
+			a.Mode = os.FileMode(mode)
+		case "modTime":
+			var ns int64
+			if ns, err = intLit(kv.Value); ns != 0 {
+				a.ModTime = time.Unix(0, ns)
+			}
+		case "compressed":
+			id, ok := kv.Value.(*ast.Ident)
+			a.compressed = ok && id.Name == "true"
+		case "encoded":
+			if sl, ok := kv.Value.(*ast.SliceExpr); ok {
+				err = l.sliceLit(a, sl)
+				break
+			}
+			var value []byte
+			value, err = valueLit(kv.Value)
+			a.values = [][]byte{value}
+		case "chunks":
+			chunks, ok := kv.Value.(*ast.CompositeLit)
+			if !ok {
+				return nil, fmt.Errorf("unexpected chunks")
+			}
+			for _, chunk := range chunks.Elts {
+				value, err := valueLit(chunk)
+				if err != nil {
+					return nil, err
+				}
+				a.values = append(a.values, value)
+			}
+		case "file":
+			a.file, err = stringLit(kv.Value)
+		case "dup":
+			if id, ok := kv.Value.(*ast.Ident); ok {
+				l.dups = append(l.dups, dup{asset: a, ref: id.Name})
+			}
+		case "block":
+			if id, ok := kv.Value.(*ast.Ident); ok {
+				l.members = append(l.members, dup{asset: a, ref: id.Name})
+			}
+		case "offset":
+			a.offset, err = intLit(kv.Value)
+		case "pakSpan":
+			err = l.spanLit(a, kv.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", key.Name, err)
+		}
+	}
+	return a, nil
+}
+
+// assets resolves the references between the variables, and returns the
+// assets of each root.
+func (l *loader) assets(c codec, e encoding, aead cipher.AEAD, dir string) ([]Asset, error) {
+	for _, b := range l.blocks {
+		b.codec, b.encoding, b.aead = c, e, aead
+	}
+	packs := make(map[string][]byte)
+	for _, s := range l.slices {
+		file, ok := l.packs[s.ref]
+		if !ok {
+			return nil, fmt.Errorf("%q refers to unknown %s", s.asset.Name, s.ref)
+		}
+		pack, ok := packs[file]
+		if !ok {
+			var err error
+			if pack, err = ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file))); err != nil {
+				return nil, err
+			}
+			packs[file] = pack
+		}
+		if s.lo > s.hi || s.hi > int64(len(pack)) {
+			return nil, fmt.Errorf("%q is out of %s", s.asset.Name, file)
+		}
+		s.asset.values, s.asset.raw = [][]byte{pack[s.lo:s.hi]}, true
+	}
+	for _, m := range l.members {
+		b, ok := l.blocks[m.ref]
+		if !ok {
+			return nil, fmt.Errorf("%q refers to unknown %s", m.asset.Name, m.ref)
+		}
+		m.asset.block = b
+	}
+	for _, d := range l.dups {
+		shared, ok := l.shared[d.ref]
+		if !ok {
+			return nil, fmt.Errorf("%q refers to unknown %s", d.asset.Name, d.ref)
+		}
+		d.asset.values, d.asset.raw = shared.values, shared.raw
+		d.asset.block, d.asset.offset = shared.block, shared.offset
+	}
+
+	parts := make(map[string]bool)
+	for _, names := range l.joins {
+		for _, name := range names {
+			parts[name] = true
+		}
+	}
+	roots := make(map[string][]loadedEntry)
+	for name, entries := range l.maps {
+		if !parts[name] {
+			roots[name] = entries
+		}
+	}
+	for name, names := range l.joins {
+		for _, part := range names {
+			roots[name] = append(roots[name], l.maps[part]...)
+		}
+	}
+
+	var assets []Asset
+	for name, entries := range roots {
+		for _, entry := range entries {
+			a := entry.asset
+			if entry.ref != "" {
+				if a = l.shared[entry.ref]; a == nil {
+					return nil, fmt.Errorf("%s refers to unknown %s", name, entry.ref)
+				}
+			}
+			loaded := *a
+			loaded.Root = strings.TrimPrefix(name, "assets")
+			loaded.codec = c
+			loaded.encoding = e
+			loaded.aead = aead
+			loaded.dir = dir
+			assets = append(assets, loaded)
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if assets[i].Root != assets[j].Root {
+			return assets[i].Root < assets[j].Root
+		}
+		return assets[i].Name < assets[j].Name
+	})
+	return assets, nil
+}
+
+func stringLit(expr ast.Expr) (string, error) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", fmt.Errorf("want a string")
+	}
+	return strconv.Unquote(lit.Value)
+}
+
+func intLit(expr ast.Expr) (int64, error) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return 0, fmt.Errorf("want an integer")
+	}
+	return strconv.ParseInt(lit.Value, 0, 64)
+}
+
+// valueLit returns the value of an encoded literal, a string or a []byte.
+func valueLit(expr ast.Expr) ([]byte, error) {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		s, err := stringLit(expr)
+		return []byte(s), err
+	}
+	value := make([]byte, 0, len(lit.Elts))
+	for _, elt := range lit.Elts {
+		b, err := intLit(elt)
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, byte(b))
+	}
+	return value, nil
+}
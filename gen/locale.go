@@ -0,0 +1,82 @@
+package gen
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// localeFile is a catalog found with Locales, named after its language tag.
+type localeFile struct {
+	Tag string
+	// Key is the tag in lower case, telling catalogs apart.
+	Key   string
+	Asset string
+	// Default is set for the catalog of DefaultLocale.
+	Default bool
+}
+
+// localeFiles returns the catalogs among the entries, sorted by tag. Each
+// must be named after a language tag, like en.json or pt-BR.json, and there
+// can only be one per tag.
+func (g *generator) localeFiles(entries []entry) ([]localeFile, error) {
+	var locales []localeFile
+	found := make(map[string]string)
+	for _, e := range entries {
+		name := e.Name
+		if e.Original != "" {
+			name = e.Original
+		}
+		if !g.locales.matchName(name) {
+			continue
+		}
+		tag := path.Base(name)
+		if i := strings.Index(tag, "."); i >= 0 {
+			tag = tag[:i]
+		}
+		tag = strings.Replace(tag, "_", "-", -1)
+		if !isLanguageTag(tag) {
+			return nil, fmt.Errorf("%q is a catalog, but isn't named after a language tag, like en or pt-BR", name)
+		}
+		key := strings.ToLower(tag)
+		if other, ok := found[key]; ok {
+			return nil, fmt.Errorf("%q and %q are both catalogs for %s", other, name, tag)
+		}
+		found[key] = name
+		locales = append(locales, localeFile{Tag: tag, Key: key, Asset: e.Name})
+	}
+	if len(locales) != 0 && g.DefaultLocale != "" {
+		if _, ok := found[strings.ToLower(g.DefaultLocale)]; !ok {
+			return nil, fmt.Errorf("there is no catalog for the default locale %s", g.DefaultLocale)
+		}
+		for i := range locales {
+			locales[i].Default = locales[i].Key == strings.ToLower(g.DefaultLocale)
+		}
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i].Key < locales[j].Key })
+	return locales, nil
+}
+
+// isLanguageTag tells if tag is written like a BCP 47 language tag: a
+// language of 2 to 8 letters, followed by subtags of 1 to 8 letters or
+// digits, separated by dashes, that golang.org/x/text/language can match.
+func isLanguageTag(tag string) bool {
+	if _, err := language.Parse(tag); err != nil {
+		return false
+	}
+	for i, sub := range strings.Split(tag, "-") {
+		if len(sub) < 1 || len(sub) > 8 || i == 0 && len(sub) < 2 {
+			return false
+		}
+		for _, r := range sub {
+			letter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+			if !letter && (i == 0 || r < '0' || r > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
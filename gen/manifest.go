@@ -0,0 +1,38 @@
+package gen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// manifestFile is the file listing the files embedded, with Manifest.
+const manifestFile = "manifest.txt"
+
+// manifestLine returns the line of the manifest listing the file of e. Names
+// which would be ambiguous are quoted.
+func manifestLine(e entry) string {
+	name := e.Name
+	if e.Original != "" {
+		name = e.Original
+	}
+	if strings.ContainsAny(name, " \t\"") || !strconv.CanBackquote(name) {
+		name = strconv.Quote(name)
+	}
+	return fmt.Sprintf("%s %d %s", name, e.Size, e.Hash)
+}
+
+// writeManifest writes the manifest of the files embedded.
+func (g *generator) writeManifest(w io.Writer) error {
+	lines := append([]string(nil), g.manifest...)
+	sort.Strings(lines)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n", generatedHeader)
+	for _, line := range lines {
+		fmt.Fprintf(bw, "%s\n", line)
+	}
+	return bw.Flush()
+}
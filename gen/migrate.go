@@ -0,0 +1,587 @@
+package gen
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The tools whose packages can be migrated.
+const (
+	GoBindata = "go-bindata"
+	Statik    = "statik"
+	Packr     = "packr"
+)
+
+// Legacy is a package generated by go-bindata, statik or packr, as read by
+// LoadLegacy.
+type Legacy struct {
+	// Tool is GoBindata, Statik or Packr.
+	Tool    string
+	PkgName string
+	// Filenames are the files written by the tool.
+	Filenames []string
+	// Files are the files embedded in the package, sorted by box and name.
+	Files []LegacyFile
+
+	// namespace is the one statik registers the files with, if any
+	namespace string
+}
+
+// LegacyFile is a file embedded in a package generated by another tool.
+type LegacyFile struct {
+	// Box is the packr box holding the file, like "./templates".
+	Box     string
+	Name    string
+	Mode    os.FileMode
+	ModTime time.Time
+	Data    []byte
+}
+
+// LoadLegacy reads the files embedded in a package generated by go-bindata,
+// statik or packr v1, found at path. It is either the directory of the
+// package, or the file written by the tool.
+func LoadLegacy(path string) (*Legacy, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	filenames := []string{path}
+	if fi.IsDir() {
+		if filenames, err = filepath.Glob(filepath.Join(path, "*.go")); err != nil {
+			return nil, err
+		}
+	}
+
+	var legacy *Legacy
+	fset := token.NewFileSet()
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		lf := newLegacyFile(file)
+		found, err := lf.read()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		if found == nil {
+			continue
+		}
+		if legacy == nil {
+			legacy = &Legacy{Tool: found.Tool, PkgName: file.Name.Name}
+		} else if legacy.Tool != found.Tool {
+			return nil, fmt.Errorf("found packages of both %s and %s at %s", legacy.Tool, found.Tool, path)
+		}
+		legacy.Filenames = append(legacy.Filenames, filename)
+		legacy.Files = append(legacy.Files, found.Files...)
+		legacy.namespace = found.namespace
+	}
+	if legacy == nil {
+		return nil, fmt.Errorf("no package generated by go-bindata, statik or packr found at %s", path)
+	}
+	sort.Slice(legacy.Files, func(i, j int) bool {
+		if legacy.Files[i].Box != legacy.Files[j].Box {
+			return legacy.Files[i].Box < legacy.Files[j].Box
+		}
+		return legacy.Files[i].Name < legacy.Files[j].Name
+	})
+	return legacy, nil
+}
+
+// legacyFile finds the files embedded in a Go file written by another tool.
+type legacyFile struct {
+	file *ast.File
+	// imports are the local names of the imported packages, by path
+	imports map[string]string
+	funcs   map[string]*ast.FuncDecl
+	// values are the expressions assigned to the variables and constants,
+	// at the top level or in functions
+	values map[string]ast.Expr
+}
+
+func newLegacyFile(file *ast.File) *legacyFile {
+	lf := &legacyFile{
+		file:    file,
+		imports: make(map[string]string),
+		funcs:   make(map[string]*ast.FuncDecl),
+		values:  make(map[string]ast.Expr),
+	}
+	for _, spec := range file.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		lf.imports[p] = name
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Recv == nil {
+				lf.funcs[n.Name.Name] = n
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) {
+					lf.values[name.Name] = n.Values[i]
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE || len(n.Lhs) != len(n.Rhs) {
+				break
+			}
+			for i, lhs := range n.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok {
+					lf.values[id.Name] = n.Rhs[i]
+				}
+			}
+		}
+		return true
+	})
+	return lf
+}
+
+// read returns the files embedded by the tool which wrote the file, or nil
+// if none did.
+func (lf *legacyFile) read() (*Legacy, error) {
+	if _, ok := lf.imports["github.com/gobuffalo/packr/v2"]; ok {
+		return nil, fmt.Errorf("packages of packr v2 aren't supported, only those of packr v1")
+	}
+	if _, ok := lf.values["_bindata"]; ok {
+		return lf.bindata()
+	}
+	if name, ok := lf.imports["github.com/rakyll/statik/fs"]; ok {
+		return lf.statik(name)
+	}
+	if name, ok := lf.imports["github.com/gobuffalo/packr"]; ok {
+		return lf.packr(name)
+	}
+	return nil, nil
+}
+
+// bindata reads the files of go-bindata, listed in the _bindata map along
+// with the function returning each of them.
+func (lf *legacyFile) bindata() (*Legacy, error) {
+	lit, ok := lf.values["_bindata"].(*ast.CompositeLit)
+	if !ok {
+		return nil, fmt.Errorf("unexpected _bindata")
+	}
+	legacy := &Legacy{Tool: GoBindata}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return nil, fmt.Errorf("unexpected _bindata element")
+		}
+		name, err := stringLit(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected _bindata key")
+		}
+		id, ok := kv.Value.(*ast.Ident)
+		if !ok || lf.funcs[id.Name] == nil {
+			return nil, fmt.Errorf("%q has no function", name)
+		}
+		f := LegacyFile{Name: name, Mode: 0644}
+		data, compressed, ok := lf.bindataData(id.Name, make(map[string]bool))
+		if !ok {
+			return nil, fmt.Errorf("%q has no data, the package may have been generated with -debug", name)
+		}
+		if f.Data, err = lf.decode(data); err != nil {
+			return nil, fmt.Errorf("%q: %v", name, err)
+		}
+		if compressed {
+			if f.Data, err = gunzip(f.Data); err != nil {
+				return nil, fmt.Errorf("couldn't decompress %q: %v", name, err)
+			}
+		}
+		lf.bindataInfo(id.Name, &f)
+		legacy.Files = append(legacy.Files, f)
+	}
+	return legacy, nil
+}
+
+// bindataData finds the variable holding the data returned by the function
+// fn, or by the functions it calls, and tells if it is decompressed with
+// bindataRead on the way.
+func (lf *legacyFile) bindataData(fn string, seen map[string]bool) (data ast.Expr, compressed, ok bool) {
+	seen[fn] = true
+	ast.Inspect(lf.funcs[fn].Body, func(n ast.Node) bool {
+		if ok {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			id, isIdent := n.Fun.(*ast.Ident)
+			if !isIdent {
+				break
+			}
+			if id.Name == "bindataRead" {
+				compressed = true
+			} else if lf.funcs[id.Name] != nil && !seen[id.Name] {
+				var called bool
+				data, called, ok = lf.bindataData(id.Name, seen)
+				compressed = compressed || called
+				return false
+			}
+		case *ast.Ident:
+			// the data is held by a variable named like _assetsAppJs
+			if value, isVar := lf.values[n.Name]; isVar && strings.HasPrefix(n.Name, "_") {
+				data, ok = value, true
+				return false
+			}
+		}
+		return true
+	})
+	return data, compressed, ok
+}
+
+// bindataInfo reads the mode and the modification time of a file from the
+// bindataFileInfo built by its function fn.
+func (lf *legacyFile) bindataInfo(fn string, f *LegacyFile) {
+	ast.Inspect(lf.funcs[fn].Body, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		if id, ok := lit.Type.(*ast.Ident); !ok || id.Name != "bindataFileInfo" {
+			return true
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, _ := kv.Key.(*ast.Ident)
+			call, ok := kv.Value.(*ast.CallExpr)
+			if key == nil || !ok || len(call.Args) == 0 {
+				continue
+			}
+			// mode: os.FileMode(420), modTime: time.Unix(1600000000, 0)
+			n, err := intLit(call.Args[0])
+			if err != nil {
+				continue
+			}
+			switch key.Name {
+			case "mode":
+				f.Mode = os.FileMode(n)
+			case "modTime":
+				if n != 0 {
+					f.ModTime = time.Unix(n, 0)
+				}
+			}
+		}
+		return false
+	})
+}
+
+// statik reads the zip archive statik registers in the init of the package,
+// with the package imported as name.
+func (lf *legacyFile) statik(name string) (*Legacy, error) {
+	legacy := &Legacy{Tool: Statik}
+	var data ast.Expr
+	ast.Inspect(lf.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || data != nil || !isCall(call, name, "Register", "RegisterWithNamespace") {
+			return true
+		}
+		if len(call.Args) == 2 {
+			legacy.namespace, _ = stringLit(call.Args[0])
+		}
+		data = call.Args[len(call.Args)-1]
+		return false
+	})
+	if data == nil {
+		return nil, fmt.Errorf("no data registered with statik")
+	}
+	archive, err := lf.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the archive: %v", err)
+	}
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		f := LegacyFile{
+			Name:    strings.TrimPrefix(path.Clean("/"+zf.Name), "/"),
+			Mode:    zf.Mode().Perm(),
+			ModTime: zf.Modified,
+		}
+		if f.Mode == 0 {
+			f.Mode = 0644
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %q: %v", zf.Name, err)
+		}
+		f.Data, err = ioutil.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %q: %v", zf.Name, err)
+		}
+		legacy.Files = append(legacy.Files, f)
+	}
+	return legacy, nil
+}
+
+// packr reads the files packr v1 packs in the init of the package, with
+// packr imported as name.
+func (lf *legacyFile) packr(name string) (*Legacy, error) {
+	legacy := &Legacy{Tool: Packr}
+	var err error
+	ast.Inspect(lf.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || err != nil || !isCall(call, name, "PackJSONBytes", "PackBytes") || len(call.Args) != 3 {
+			return true
+		}
+		f := LegacyFile{Mode: 0644}
+		if f.Box, err = stringLit(call.Args[0]); err != nil {
+			return false
+		}
+		if f.Name, err = stringLit(call.Args[1]); err != nil {
+			return false
+		}
+		if f.Data, err = lf.decode(call.Args[2]); err != nil {
+			return false
+		}
+		if call.Fun.(*ast.SelectorExpr).Sel.Name == "PackJSONBytes" {
+			// the bytes are marshaled to JSON, as base64
+			var data []byte
+			if err = json.Unmarshal(f.Data, &data); err != nil {
+				err = fmt.Errorf("%q: %v", f.Name, err)
+				return false
+			}
+			f.Data = data
+		}
+		// packr decompresses the files it finds compressed
+		if bytes.HasPrefix(f.Data, []byte{0x1f, 0x8b}) {
+			if f.Data, err = gunzip(f.Data); err != nil {
+				err = fmt.Errorf("couldn't decompress %q: %v", f.Name, err)
+				return false
+			}
+		}
+		legacy.Files = append(legacy.Files, f)
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return legacy, nil
+}
+
+// isCall tells if call calls one of funcs of the package imported as pkg.
+func isCall(call *ast.CallExpr, pkg string, funcs ...string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	if id, ok := sel.X.(*ast.Ident); !ok || id.Name != pkg {
+		return false
+	}
+	for _, name := range funcs {
+		if sel.Sel.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decode returns the data held by expr, a string or []byte literal, a
+// conversion of one to []byte, a concatenation of them, or a variable or
+// constant holding one.
+func (lf *legacyFile) decode(expr ast.Expr) ([]byte, error) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		value, ok := lf.values[expr.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown value %s", expr.Name)
+		}
+		return lf.decode(value)
+	case *ast.ParenExpr:
+		return lf.decode(expr.X)
+	case *ast.CallExpr:
+		if len(expr.Args) != 1 {
+			break
+		}
+		if at, ok := expr.Fun.(*ast.ArrayType); ok && at.Len == nil {
+			return lf.decode(expr.Args[0])
+		}
+	case *ast.BinaryExpr:
+		if expr.Op != token.ADD {
+			break
+		}
+		x, err := lf.decode(expr.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := lf.decode(expr.Y)
+		if err != nil {
+			return nil, err
+		}
+		return append(x, y...), nil
+	case *ast.BasicLit, *ast.CompositeLit:
+		return valueLit(expr)
+	}
+	return nil, fmt.Errorf("unexpected data")
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(gr)
+}
+
+// Migrate writes the files embedded in a package generated by another tool
+// to dir, by default a directory named after opts.Name in the package, and
+// generates a package holding them with opts in its place, merged under
+// opts.Name. The files of the tool are removed, and replaced by a file
+// keeping its API on top of the generated one, so that the code using the
+// package can move to it over time. That file is left alone by later
+// generations.
+//
+// By default, the package keeps the name and the directory of the one
+// generated by go-bindata or statik. The files of packr live among the code
+// using them, so they are moved to a package of their own, to be imported
+// where packr was used.
+func Migrate(ctx context.Context, legacy *Legacy, dir string, opts Options) error {
+	legacyDir := filepath.Dir(legacy.Filenames[0])
+	if opts.PkgName == "" && legacy.Tool != Packr {
+		opts.PkgName = legacy.PkgName
+	}
+	if opts.PkgName == "" {
+		opts.PkgName = "staticfs"
+	}
+	if opts.Output == "" {
+		opts.Output = legacyDir
+		if legacy.Tool == Packr {
+			opts.Output = filepath.Join(legacyDir, opts.PkgName)
+		}
+	}
+	if opts.Name == "" {
+		opts.Name = "assets"
+	}
+	if opts.Out != "" {
+		return fmt.Errorf("a migrated package can't be a single file")
+	}
+	if dir == "" {
+		dir = filepath.Join(opts.Output, opts.Name)
+	}
+
+	// packr boxes are kept apart in the directory
+	var boxes []legacyBox
+	for _, f := range legacy.Files {
+		prefix := ""
+		if legacy.Tool == Packr {
+			prefix = strings.TrimPrefix(path.Clean("/"+f.Box), "/")
+			if len(boxes) == 0 || boxes[len(boxes)-1].Name != f.Box {
+				boxes = append(boxes, legacyBox{Name: f.Box, Prefix: prefix})
+			}
+		}
+		name := path.Join(prefix, f.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("refusing to write %q outside of %q", f.Name, dir)
+		}
+		if err := writeLegacyFile(filepath.Join(dir, filepath.FromSlash(name)), f); err != nil {
+			return err
+		}
+	}
+
+	opts.Dirs = []string{dir}
+	opts.Merge = true
+	opts.TrimPrefix = dir
+	if err := Generate(ctx, opts); err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(opts.Output, dir)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+	out := make(generated)
+	out.execute(compatFilename(legacy.Tool), compattempl, struct {
+		Tool      string
+		PkgName   string
+		RootName  string
+		Generate  string
+		Namespace string
+		Boxes     []legacyBox
+	}{
+		Tool:      legacy.Tool,
+		PkgName:   opts.PkgName,
+		RootName:  camelize(opts.Name),
+		Generate:  fmt.Sprintf("gostatic -merge -name %s -pkgname %s -o . -trim-prefix %s %s", opts.Name, opts.PkgName, rel, rel),
+		Namespace: legacy.namespace,
+		Boxes:     boxes,
+	})
+	if err := out.write(opts.Output); err != nil {
+		return fmt.Errorf("couldn't write the %s API: %v", legacy.Tool, err)
+	}
+
+	for _, filename := range legacy.Filenames {
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+		if opts.Log != nil {
+			opts.Log.Printf("Removed %q, generated by %s", filename, legacy.Tool)
+		}
+	}
+	return nil
+}
+
+// legacyBox is a packr box, which files are written under Prefix.
+type legacyBox struct {
+	Name   string
+	Prefix string
+}
+
+// compatFilename names the file keeping the API of tool.
+func compatFilename(tool string) string {
+	return snakify(strings.TrimPrefix(tool, "go-")) + "_compat.go"
+}
+
+// writeLegacyFile writes f to filename, which mustn't exist.
+func writeLegacyFile(filename string, f LegacyFile) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, f.Mode.Perm())
+	if os.IsExist(err) {
+		return fmt.Errorf("%s exists already", filename)
+	} else if err != nil {
+		return err
+	}
+	_, err = file.Write(f.Data)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if !f.ModTime.IsZero() {
+		return os.Chtimes(filename, f.ModTime, f.ModTime)
+	}
+	return nil
+}
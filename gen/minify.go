@@ -0,0 +1,53 @@
+package gen
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+	"github.com/tdewolff/minify/v2/json"
+	"github.com/tdewolff/minify/v2/svg"
+)
+
+// minifiedTypes are the media types of the files minified, by extension.
+// They don't depend on the MIME types known to the system, so that the
+// package is the same everywhere.
+var minifiedTypes = map[string]string{
+	".html":        "text/html",
+	".htm":         "text/html",
+	".css":         "text/css",
+	".js":          "application/javascript",
+	".mjs":         "application/javascript",
+	".svg":         "image/svg+xml",
+	".json":        "application/json",
+	".map":         "application/json",
+	".webmanifest": "application/json",
+}
+
+func newMinifier() *minify.M {
+	m := minify.New()
+	m.AddFunc("text/html", html.Minify)
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("application/javascript", js.Minify)
+	m.AddFunc("image/svg+xml", svg.Minify)
+	m.AddFunc("application/json", json.Minify)
+	return m
+}
+
+// minify returns the content of the file called name minified, if it is
+// HTML, CSS, JavaScript, SVG or JSON, and as it is otherwise.
+func (g *generator) minify(name string, data []byte) ([]byte, error) {
+	mediatype, ok := minifiedTypes[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return data, nil
+	}
+	minified, err := g.minifier.Bytes(mediatype, data)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't minify %q: %v", name, err)
+	}
+	return minified, nil
+}
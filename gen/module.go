@@ -0,0 +1,95 @@
+package gen
+
+import (
+	"go/token"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// importPath returns the import path of the package written to dir, found
+// from the go.mod of the module holding it, or "" if it isn't in a module.
+func importPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		if data, err := ioutil.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+			module := modulePath(data)
+			rel, err := filepath.Rel(root, abs)
+			if module == "" || err != nil {
+				return ""
+			}
+			return path.Join(module, filepath.ToSlash(rel))
+		}
+		if filepath.Dir(root) == root {
+			return ""
+		}
+	}
+}
+
+// modulePath returns the path of the module declared by the content of a
+// go.mod file.
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+			return unquoted
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+// validPkgName tells if name can be that of a package, an identifier other
+// than the blank one.
+func validPkgName(name string) bool {
+	return token.IsIdentifier(name) && name != "_"
+}
+
+// whyInvalid tells why name isn't a valid package name.
+func whyInvalid(name string) string {
+	first, _ := utf8.DecodeRuneInString(name)
+	switch {
+	case name == "":
+		return "it is empty"
+	case name == "_":
+		return "it is the blank identifier"
+	case token.IsKeyword(name):
+		return "it is a Go keyword"
+	case unicode.IsDigit(first):
+		return "it starts with a digit"
+	}
+	return "it holds characters other than letters, digits and underscores"
+}
+
+// sanitizePkgName returns a valid package name close to name, in lower case
+// and without the characters other than letters and digits, as package names
+// go.
+func sanitizePkgName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	sanitized := b.String()
+	first, _ := utf8.DecodeRuneInString(sanitized)
+	switch {
+	case sanitized == "":
+		return "staticfs"
+	case token.IsKeyword(sanitized):
+		return sanitized + "pkg"
+	case unicode.IsDigit(first):
+		return "pkg" + sanitized
+	}
+	return sanitized
+}
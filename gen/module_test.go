@@ -0,0 +1,35 @@
+package gen
+
+import "testing"
+
+// TestSanitizePkgName checks that invalid package names are rejected, and
+// turned into valid ones.
+func TestSanitizePkgName(t *testing.T) {
+	tests := []struct {
+		name      string
+		valid     bool
+		sanitized string
+	}{
+		{name: "staticfs", valid: true, sanitized: "staticfs"},
+		{name: "static_fs", valid: true, sanitized: "staticfs"},
+		{name: "_", sanitized: "staticfs"},
+		{name: "__", valid: true, sanitized: "staticfs"},
+		{name: "", sanitized: "staticfs"},
+		{name: "type", sanitized: "typepkg"},
+		{name: "2d", sanitized: "pkg2d"},
+		{name: "web-assets", sanitized: "webassets"},
+		{name: "Été", valid: true, sanitized: "été"},
+	}
+	for _, tt := range tests {
+		if valid := validPkgName(tt.name); valid != tt.valid {
+			t.Errorf("validPkgName(%q) = %t, want %t", tt.name, valid, tt.valid)
+		}
+		sanitized := sanitizePkgName(tt.name)
+		if sanitized != tt.sanitized {
+			t.Errorf("sanitizePkgName(%q) = %q, want %q", tt.name, sanitized, tt.sanitized)
+		}
+		if !validPkgName(sanitized) {
+			t.Errorf("sanitizePkgName(%q) = %q, which isn't valid: %s", tt.name, sanitized, whyInvalid(sanitized))
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build/constraint"
+	"go/format"
+	"go/scanner"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// generatedHeader starts every file written by gostatic, after the license
+// of custom templates.
+const generatedHeader = "// GENERATED FILE: Do not edit, all changes will be lost."
+
+// generated holds the files of the package, keyed by their name in the
+// package directory. They are only rendered when written or checked, one at a
+// time, so that the content of the files embedded is streamed from the spool.
+type generated map[string]renderer
+
+// renderer writes a generated file.
+type renderer func(w io.Writer) error
+
+// execute renders the file from templ, and formats it like gofmt. Code that
+// doesn't parse is an error rather than a package that doesn't compile.
+func (g generated) execute(filename string, templ *template.Template, data interface{}) {
+	g[filename] = func(w io.Writer) error {
+		buf := bytes.NewBuffer(nil)
+		if err := templ.Execute(buf, data); err != nil {
+			return err
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return invalidSource(buf.Bytes(), err)
+		}
+		_, err = w.Write(src)
+		return err
+	}
+}
+
+// executeSpooled renders the file from templ like execute, for files holding
+// the literals of s. They are streamed from the spool into the formatted
+// code, rather than formatted along with it.
+func (g generated) executeSpooled(filename string, templ *template.Template, data interface{}, s *spool) {
+	g[filename] = func(w io.Writer) error {
+		buf := bytes.NewBuffer(nil)
+		if err := templ.Execute(buf, data); err != nil {
+			return err
+		}
+		return s.format(w, buf.Bytes())
+	}
+}
+
+// constrain wraps r so that the file it renders is only built when expr is
+// satisfied, along with the build constraint the file has already. The
+// constraint goes after the generated header if the file has none.
+func constrain(r renderer, expr constraint.Expr) renderer {
+	return func(w io.Writer) error {
+		pr, pw := io.Pipe()
+		go func() { _ = pw.CloseWithError(r(pw)) }()
+		defer func() { _ = pr.Close() }()
+
+		br := bufio.NewReader(pr)
+		bw := bufio.NewWriter(w)
+		var header []string
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("no package clause: %v", err)
+			}
+			if strings.HasPrefix(line, "package ") {
+				header = append(header, line)
+				break
+			}
+			header = append(header, line)
+		}
+
+		build := "//go:build " + expr.String() + "\n"
+		at := -1
+		for i, line := range header {
+			if constraint.IsGoBuild(line) {
+				x, err := constraint.Parse(strings.TrimSpace(line))
+				if err != nil {
+					return err
+				}
+				header[i] = "//go:build " + (&constraint.AndExpr{X: expr, Y: x}).String() + "\n"
+				build, at = "", -1
+				break
+			}
+			if strings.TrimSpace(line) == generatedHeader {
+				at = i + 1
+			}
+		}
+		if build != "" && at < 0 {
+			_, _ = bw.WriteString(build + "\n")
+		}
+		for i, line := range header {
+			_, _ = bw.WriteString(line)
+			if build != "" && i+1 == at {
+				_, _ = bw.WriteString("\n" + build)
+			}
+		}
+		if _, err := io.Copy(bw, br); err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+}
+
+// invalidSource describes the error found parsing src, along with the line it
+// is found on.
+func invalidSource(src []byte, err error) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return fmt.Errorf("generated invalid code: %v", err)
+	}
+	pos := list[0].Pos
+	lines := bytes.Split(src, []byte("\n"))
+	if pos.Line < 1 || pos.Line > len(lines) {
+		return fmt.Errorf("generated invalid code: %v", list[0])
+	}
+	line := string(lines[pos.Line-1])
+	// lines holding data are long, keep the part around the error
+	const context = 40
+	if start := pos.Column - 1 - context; start > 0 && start < len(line) {
+		line = "..." + line[start:]
+	}
+	if len(line) > 3*context {
+		line = line[:3*context] + "..."
+	}
+	return fmt.Errorf("generated invalid code: %v\n\t%s", list[0], line)
+}
+
+func (g generated) filenames() []string {
+	filenames := make([]string, 0, len(g))
+	for filename := range g {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	return filenames
+}
+
+// single combines the generated files into one, called filename, to be
+// dropped in an existing package named pkgname. Their imports are merged, and
+// test files are left out. The files which aren't Go source, the packs of
+// the bundle backend, are kept as they are. Each file is rendered to a
+// temporary file first, to find its imports.
+func (g generated) single(filename, pkgname string) generated {
+	single := make(generated)
+	var names []string
+	for _, name := range g.filenames() {
+		switch {
+		case filepath.Ext(name) != ".go":
+			single[name] = g[name]
+		case !strings.HasSuffix(name, "_test.go"):
+			names = append(names, name)
+		}
+	}
+	single[filename] = func(w io.Writer) error {
+		imports := make(map[string]bool)
+		var bodies []*bufio.Reader
+		for _, name := range names {
+			tmp, err := ioutil.TempFile("", "gostatic-")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = tmp.Close()
+				_ = os.Remove(tmp.Name())
+			}()
+			if err := render(tmp, g[name]); err != nil {
+				return fmt.Errorf("couldn't render %s: %v", name, err)
+			}
+			if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			body := bufio.NewReader(tmp)
+			if err := readImports(body, imports); err != nil {
+				return fmt.Errorf("couldn't read the imports of %s: %v", name, err)
+			}
+			bodies = append(bodies, body)
+		}
+
+		var std, external []string
+		for path := range imports {
+			if unquoted, _ := strconv.Unquote(path); strings.Contains(strings.Split(unquoted, "/")[0], ".") {
+				external = append(external, path)
+			} else {
+				std = append(std, path)
+			}
+		}
+		sort.Strings(std)
+		sort.Strings(external)
+
+		bw := bufio.NewWriter(w)
+		fmt.Fprintf(bw, "%s\n\npackage %s\n", generatedHeader, pkgname)
+		if len(imports) != 0 {
+			bw.WriteString("\nimport (\n")
+			for _, path := range std {
+				fmt.Fprintf(bw, "\t%s\n", path)
+			}
+			if len(std) != 0 && len(external) != 0 {
+				bw.WriteString("\n")
+			}
+			for _, path := range external {
+				fmt.Fprintf(bw, "\t%s\n", path)
+			}
+			bw.WriteString(")\n")
+		}
+		for _, body := range bodies {
+			bw.WriteString("\n")
+			if _, err := io.Copy(bw, body); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	}
+	return single
+}
+
+// suffixed returns the generated files with suffix added to the names of the
+// Go files, before _test.go for the tests.
+func (g generated) suffixed(suffix string) generated {
+	out := make(generated, len(g))
+	for filename, r := range g {
+		switch {
+		case strings.HasSuffix(filename, "_test.go"):
+			filename = strings.TrimSuffix(filename, "_test.go") + suffix + "_test.go"
+		case filepath.Ext(filename) == ".go":
+			filename = strings.TrimSuffix(filename, ".go") + suffix + ".go"
+		}
+		out[filename] = r
+	}
+	return out
+}
+
+// readImports reads a generated file up to its first declaration, adding the
+// quoted paths it imports to imports.
+func readImports(r *bufio.Reader, imports map[string]bool) error {
+	inPackage, inImports := false, false
+	for {
+		// declarations start at the first line that isn't part of the
+		// header, so it must be left for the body
+		peek, err := r.Peek(1)
+		if err != nil {
+			return err
+		}
+		if inPackage && !inImports && peek[0] != '\n' && peek[0] != 'i' {
+			return nil
+		}
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case !inPackage:
+			inPackage = strings.HasPrefix(line, "package ")
+		case inImports && line == ")":
+			inImports = false
+		case inImports && line != "":
+			imports[line] = true
+		case line == "import (":
+			inImports = true
+		case strings.HasPrefix(line, "import "):
+			imports[strings.TrimSpace(line[len("import "):])] = true
+		}
+	}
+}
+
+// render writes a generated file to w, buffered.
+func render(w io.Writer, r renderer) error {
+	bw := bufio.NewWriter(w)
+	if err := r(bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// write saves the generated files in dir, replacing the files generated
+// before. It refuses to replace any other file.
+func (g generated) write(dir string) error {
+	s, err := g.stage(dir)
+	if err != nil {
+		return err
+	}
+	return s.commit()
+}
+
+// stage renders the generated files next to their place in dir, to be put
+// there by committing them. It refuses to replace any file that wasn't
+// generated by gostatic, and leaves nothing behind on error.
+func (g generated) stage(dir string) (staged, error) {
+	for _, filename := range g.filenames() {
+		isgen, err := isGenerated(filepath.Join(dir, filename))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil && !isgen {
+			return nil, fmt.Errorf("%s exists and wasn't generated by gostatic", filepath.Join(dir, filename))
+		}
+	}
+	var s staged
+	for _, filename := range g.filenames() {
+		// files starting with a dot are ignored by the go tool, should one
+		// be left behind
+		file, err := ioutil.TempFile(dir, "."+filename+".tmp")
+		if err != nil {
+			s.rollback()
+			return nil, err
+		}
+		s = append(s, stagedFile{tmp: file.Name(), dst: filepath.Join(dir, filename)})
+		err = render(file, g[filename])
+		if err == nil {
+			err = file.Chmod(0644)
+		}
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			s.rollback()
+			return nil, fmt.Errorf("couldn't write %s: %v", filename, err)
+		}
+		// a file that didn't change is left alone, keeping its modification
+		// time for the build tools
+		if same, err := sameContent(file.Name(), filepath.Join(dir, filename)); err == nil && same {
+			s[len(s)-1:].rollback()
+			s = s[:len(s)-1]
+		}
+	}
+	return s, nil
+}
+
+// sameContent tells if the files called a and b hold the same content.
+func sameContent(a, b string) (bool, error) {
+	afi, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bfi, err := os.Stat(b)
+	if err != nil || afi.Size() != bfi.Size() || !bfi.Mode().IsRegular() {
+		return false, err
+	}
+	adata, err := ioutil.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	bdata, err := ioutil.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(adata, bdata), nil
+}
+
+// staged are files and directories written aside, until all of them are
+// written and they can replace the ones in place.
+type staged []stagedFile
+
+type stagedFile struct {
+	tmp string
+	dst string
+}
+
+// commit moves the staged files in place. Directories in place are replaced
+// as a whole.
+func (s staged) commit() error {
+	for i, f := range s {
+		if fi, err := os.Stat(f.tmp); err == nil && fi.IsDir() {
+			if err := os.RemoveAll(f.dst); err != nil {
+				s[i:].rollback()
+				return err
+			}
+		}
+		if err := os.Rename(f.tmp, f.dst); err != nil {
+			s[i:].rollback()
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback removes the staged files.
+func (s staged) rollback() {
+	for _, f := range s {
+		_ = os.RemoveAll(f.tmp)
+	}
+}
+
+// check compares the generated files with the ones found in dir, returning a
+// summary of each difference. Stale files are differences too, unless
+// keepStale is set, among the Go files suffixed with suffix if set.
+func (g generated) check(dir string, keepStale bool, suffix string) ([]string, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return []string{fmt.Sprintf("%s: missing directory", dir)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var diffs []string
+	for _, filename := range g.filenames() {
+		have, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		if os.IsNotExist(err) {
+			diffs = append(diffs, fmt.Sprintf("%s: missing", filename))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		want := bytes.NewBuffer(nil)
+		if err := g[filename](want); err != nil {
+			return nil, fmt.Errorf("couldn't render %s: %v", filename, err)
+		}
+		if !bytes.Equal(have, want.Bytes()) {
+			diffs = append(diffs, fmt.Sprintf("%s: %s", filename, summarizeDiff(have, want.Bytes())))
+		}
+	}
+
+	if keepStale {
+		return diffs, nil
+	}
+	stale, err := g.stale(dir, suffix)
+	if err != nil {
+		return nil, err
+	}
+	for _, filename := range stale {
+		diffs = append(diffs, fmt.Sprintf("%s: no longer generated", filename))
+	}
+	return diffs, nil
+}
+
+// stale lists the files of dir that were generated before, but aren't
+// anymore. If suffix is set, only the Go files suffixed with it are.
+func (g generated) stale(dir, suffix string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, fi := range infos {
+		ext := filepath.Ext(fi.Name())
+		if _, ok := g[fi.Name()]; ok || fi.IsDir() || (ext != ".go" && ext != packExt && ext != pakExt && fi.Name() != manifestFile) {
+			continue
+		}
+		if ext == ".go" && suffix != "" && !strings.HasSuffix(fi.Name(), suffix+".go") && !strings.HasSuffix(fi.Name(), suffix+"_test.go") {
+			continue
+		}
+		isgen, err := isGenerated(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if isgen {
+			stale = append(stale, fi.Name())
+		}
+	}
+	return stale, nil
+}
+
+// isGenerated tells if the file was written by gostatic.
+func isGenerated(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = file.Close() }()
+
+	// the header may follow a license, from a custom template
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == generatedHeader {
+			return true, nil
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return false, nil
+}
+
+// assetDecl matches the lines declaring assets, `"name": {name: "name", ...`,
+// capturing the quoted name.
+var assetDecl = regexp.MustCompile(`^\s*("(?:[^"\\]|\\.)*"):\s+\{name: "`)
+
+// summarizeDiff counts the lines that changed between two versions of a
+// file, and names the assets found on those lines.
+func summarizeDiff(old, new []byte) string {
+	count := make(map[string]int)
+	for _, line := range strings.Split(string(old), "\n") {
+		count[line]++
+	}
+	for _, line := range strings.Split(string(new), "\n") {
+		count[line]--
+	}
+
+	added, removed := 0, 0
+	assets := make(map[string]bool)
+	for line, n := range count {
+		switch {
+		case n > 0:
+			removed += n
+		case n < 0:
+			added -= n
+		default:
+			continue
+		}
+		if m := assetDecl.FindStringSubmatch(line); m != nil {
+			if name, err := strconv.Unquote(m[1]); err == nil {
+				assets[name] = true
+			}
+		}
+	}
+
+	summary := fmt.Sprintf("%d lines added, %d removed", added, removed)
+	if len(assets) != 0 {
+		names := make([]string, 0, len(assets))
+		for name := range assets {
+			names = append(names, strconv.Quote(name))
+		}
+		sort.Strings(names)
+		summary += ", assets changed: " + strings.Join(names, ", ")
+	}
+	return summary
+}
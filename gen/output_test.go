@@ -0,0 +1,34 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSummarizeDiff checks that only the lines declaring assets name them.
+func TestSummarizeDiff(t *testing.T) {
+	old := strings.Join([]string{
+		`import (`,
+		`	"net/http"`,
+		`)`,
+		`var filesStatic = map[string]*asset{`,
+		`	"a.css":      {name: "a.css", size: 3},`,
+		`	"img/\"b\".png": {name: "img/\"b\".png", size: 4},`,
+		`	"c.js":       sharedStatic1,`,
+		`}`,
+	}, "\n")
+	new := strings.Join([]string{
+		`import (`,
+		`	"net/url"`,
+		`)`,
+		`var filesStatic = map[string]*asset{`,
+		`	"a.css":      {name: "a.css", size: 5},`,
+		`	"img/\"b\".png": {name: "img/\"b\".png", size: 4},`,
+		`	"c.js":       sharedStatic1,`,
+		`}`,
+	}, "\n")
+	want := `2 lines added, 2 removed, assets changed: "a.css"`
+	if got := summarizeDiff([]byte(old), []byte(new)); got != want {
+		t.Errorf("summarizeDiff() = %q, want %q", got, want)
+	}
+}
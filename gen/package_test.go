@@ -0,0 +1,282 @@
+package gen
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// goTest adds a test file holding tests to the package generated in dir, and
+// runs its tests with the go command, failing t with their output if they
+// don't pass.
+func goTest(t *testing.T, dir, tests string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, "package_test.go"), []byte(tests), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "test", "-count=1", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("the tests of the generated package failed: %v\n%s", err, out)
+	}
+}
+
+// packageDir returns a directory to generate a package in, removed once the
+// test is done. It is made in the directory of this package rather than in a
+// temporary one, so that the generated package is built in the same module,
+// requiring what it imports, like golang.org/x/text.
+func packageDir(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building the generated package takes a while")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command isn't found")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir(wd, "generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+// TestPackage checks that the generated package builds, and that its
+// functions, handlers and FS find the files the way they are documented to.
+func TestPackage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "assets")
+	writeFiles(t, dir, map[string][]byte{
+		"index.html":           []byte("<p>home</p>"),
+		"docs/index.html":      []byte("<p>docs</p>"),
+		"css/App.css":          []byte("body { color: red }"),
+		"lru/a.txt":            []byte(strings.Repeat("a", 600)),
+		"lru/b.txt":            []byte(strings.Repeat("b", 600)),
+		"locales/en.json":      []byte(`{"hello": "hello"}`),
+		"locales/fr.json":      []byte(`{"hello": "bonjour"}`),
+		"locales/pt-BR.json":   []byte(`{"hello": "olá"}`),
+		"locales/zh-Hant.json": []byte(`{"hello": "你好"}`),
+	})
+	if err := os.MkdirAll(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out := packageDir(t)
+	generate(t, Options{
+		Dirs:          []string{dir},
+		TrimPrefix:    dir,
+		Output:        out,
+		PkgName:       "staticfs",
+		HTTP:          true,
+		IOFS:          true,
+		Precompressed: true,
+		CacheControl:  "public, max-age=60",
+		EmptyDirs:     true,
+		IgnoreCase:    true,
+		Locales:       []string{"locales/*.json"},
+		DefaultLocale: "en",
+		MemoryBudget:  1000,
+	})
+	goTest(t, out, packageTests)
+}
+
+// packageTests are the tests run in the package generated by TestPackage.
+const packageTests = `package staticfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	for _, name := range []string{"css/App.css", "css/app.css", "CSS/APP.CSS"} {
+		r, ok := GetAssets(name)
+		if !ok {
+			t.Errorf("%q isn't found", name)
+			continue
+		}
+		if data, _ := ioutil.ReadAll(r); string(data) != "body { color: red }" {
+			t.Errorf("%q holds %q", name, data)
+		}
+	}
+	if _, ok := GetAssets("css/missing.css"); ok {
+		t.Error("found a file that wasn't embedded")
+	}
+}
+
+func TestFS(t *testing.T) {
+	fsys := FSAssets()
+	if data, err := fsys.ReadFile("CSS/app.css"); err != nil || string(data) != "body { color: red }" {
+		t.Errorf("ReadFile ignoring case = %q, %v", data, err)
+	}
+	if fi, err := fs.Stat(fsys, "empty"); err != nil || !fi.IsDir() {
+		t.Errorf("the empty directory isn't found: %v", err)
+	}
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		names = append(names, name)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names, " "); !strings.Contains(got, " empty ") || !strings.Contains(got, " css/App.css ") {
+		t.Errorf("walked %s", got)
+	}
+	if _, err := fsys.ReadFile("../index.html"); err == nil {
+		t.Error("read a file outside of the FS")
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	fsys := Overlay(FSAssets(), DirStore("testdata/missing"))
+	if data, err := fsys.ReadFile("index.html"); err != nil || string(data) != "<p>home</p>" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+	entries, err := fsys.ReadDir(".")
+	if err != nil || len(entries) == 0 {
+		t.Errorf("ReadDir = %v, %v", entries, err)
+	}
+}
+
+func serve(h http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	h := HandlerAssets("/static/")
+
+	w := serve(h, "/static/CSS/app.css", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "body { color: red }" {
+		t.Fatalf("got %d %q", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	if w := serve(h, "/static/css/App.css", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Errorf("conditional request got %d", w.Code)
+	}
+	if w := serve(h, "/static/css/App.css", http.Header{"Range": {"bytes=0-3"}}); w.Code != http.StatusPartialContent || w.Body.String() != "body" {
+		t.Errorf("range request got %d %q", w.Code, w.Body)
+	}
+	if w := serve(h, "/static/docs", nil); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "docs/" {
+		t.Errorf("directory got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := serve(h, "/static/docs/", nil); w.Code != http.StatusOK || w.Body.String() != "<p>docs</p>" {
+		t.Errorf("index got %d %q", w.Code, w.Body)
+	}
+	if w := serve(h, "/static/missing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing file got %d", w.Code)
+	}
+	if w := serve(h, "/other/index.html", nil); w.Code != http.StatusNotFound {
+		t.Errorf("file out of the prefix got %d", w.Code)
+	}
+}
+
+func TestSPAHandler(t *testing.T) {
+	h := SPAHandlerAssets("", "index.html")
+	if w := serve(h, "/app/route", nil); w.Code != http.StatusOK || w.Body.String() != "<p>home</p>" {
+		t.Errorf("got %d %q", w.Code, w.Body)
+	}
+}
+
+func TestPrecompressed(t *testing.T) {
+	h := HandlerAssets("")
+	w := serve(h, "/lru/a.txt", http.Header{"Accept-Encoding": {"gzip"}})
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadAll(zr); err != nil || string(data) != strings.Repeat("a", 600) {
+		t.Errorf("gunzipped %d bytes, %v", len(data), err)
+	}
+	w = serve(h, "/lru/a.txt", nil)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != strings.Repeat("a", 600) {
+		t.Errorf("without gzip, got %q encoded, %d bytes", w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	f, err := HTTPAssets().Open("/empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := f.Stat(); !fi.IsDir() {
+		t.Error("the empty directory isn't a directory")
+	}
+	if _, err := HTTPAssets().Open("/css/APP.css"); err != nil {
+		t.Errorf("ignoring case: %v", err)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"fr", "bonjour"},
+		{"fr-CA", "bonjour"},
+		{"FR_ca", "bonjour"},
+		{"pt", "olá"},
+		{"pt-PT", "olá"},
+		{"zh-TW", "你好"},
+		{"de-DE, fr;q=0.8, en;q=0.5", "bonjour"},
+		{"de", "hello"},
+		{"", "hello"},
+	}
+	for _, tt := range tests {
+		data, ok := LocaleAssets(tt.tag)
+		if !ok || !bytes.Contains(data, []byte(tt.want)) {
+			t.Errorf("LocaleAssets(%q) = %s, %t, want %q", tt.tag, data, ok, tt.want)
+		}
+	}
+}
+
+func TestMemoryBudget(t *testing.T) {
+	a, _ := lookupAssets("lru/a.txt")
+	b, _ := lookupAssets("lru/b.txt")
+	for i := 0; i < 3; i++ {
+		if a.content() != strings.Repeat("a", 600) {
+			t.Fatal("lru/a.txt isn't read back")
+		}
+		if b.content() != strings.Repeat("b", 600) {
+			t.Fatal("lru/b.txt isn't read back")
+		}
+		if lru.bytes > MemoryBudget {
+			t.Errorf("%d bytes kept, over the budget of %d", lru.bytes, MemoryBudget)
+		}
+		if a.used != nil {
+			t.Error("the least recently used file is kept")
+		}
+	}
+}
+`
@@ -0,0 +1,72 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// pipe runs the files matching a glob through a command, whose output is
+// embedded in their place.
+type pipe struct {
+	glob    globs
+	command string
+}
+
+// parsePipes reads rules written `glob=command`.
+func parsePipes(rules []string) ([]pipe, error) {
+	var pipes []pipe
+	for _, rule := range rules {
+		i := strings.Index(rule, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%q isn't written glob=command", rule)
+		}
+		glob, err := parseGlobs([]string{rule[:i]})
+		if err != nil {
+			return nil, err
+		}
+		command := strings.TrimSpace(rule[i+1:])
+		if len(glob) == 0 || command == "" {
+			return nil, fmt.Errorf("%q isn't written glob=command", rule)
+		}
+		pipes = append(pipes, pipe{glob: glob, command: command})
+	}
+	return pipes, nil
+}
+
+// pipe runs the content of the file called name, embedded as key, through
+// the command of the first rule matching key, if any. The command is run by
+// the shell, with the content on its standard input and the path of the file
+// in GOSTATIC_FILE, and killed if ctx is done first.
+func (g *generator) pipe(ctx context.Context, name, key string, data []byte) ([]byte, error) {
+	for _, p := range g.pipes {
+		if !p.glob.matchName(key) {
+			continue
+		}
+		cmd := shellCommand(ctx, p.command)
+		cmd.Env = append(os.Environ(), "GOSTATIC_FILE="+name)
+		cmd.Stdin = bytes.NewReader(data)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%v: %s", err, msg)
+			}
+			return nil, fmt.Errorf("couldn't run %q on %q: %v", p.command, name, err)
+		}
+		g.vlogf("Ran %q on %q", p.command, name)
+		return stdout.Bytes(), nil
+	}
+	return data, nil
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
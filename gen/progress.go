@@ -0,0 +1,93 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// progressWidth is the number of characters of the bar itself.
+const progressWidth = 30
+
+// progress draws a bar of the files of a directory read and compressed, on a
+// single line redrawn as they are.
+type progress struct {
+	w     io.Writer
+	label string
+	files int
+	bytes int64
+	start time.Time
+
+	mu        sync.Mutex
+	doneFiles int
+	doneBytes int64
+	drawn     time.Time
+	width     int
+}
+
+func newProgress(w io.Writer, label string, files int, bytes int64) *progress {
+	p := &progress{w: w, label: label, files: files, bytes: bytes, start: time.Now()}
+	p.draw()
+	return p
+}
+
+// add counts a file of size bytes as done.
+func (p *progress) add(size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.doneFiles++
+	p.doneBytes += size
+	// redrawing for every file would slow down the terminal
+	if time.Since(p.drawn) >= 100*time.Millisecond || p.doneFiles == p.files {
+		p.draw()
+	}
+}
+
+// interrupt clears the bar to have f print something, and draws it again
+// below.
+func (p *progress) interrupt(f func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	f()
+	p.draw()
+}
+
+// done draws the bar a last time and moves on to the next line.
+func (p *progress) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.draw()
+	_, _ = fmt.Fprintln(p.w)
+}
+
+func (p *progress) draw() {
+	ratio := 1.0
+	if p.bytes > 0 {
+		ratio = float64(p.doneBytes) / float64(p.bytes)
+	}
+	filled := int(ratio * progressWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+	line := fmt.Sprintf("%s [%s] %d/%d files, %s/%s", p.label, bar,
+		p.doneFiles, p.files, humanize.Bytes(uint64(p.doneBytes)), humanize.Bytes(uint64(p.bytes)))
+	if elapsed := time.Since(p.start); ratio > 0 && ratio < 1 && elapsed > time.Second {
+		left := time.Duration(float64(elapsed) * (1 - ratio) / ratio)
+		line += fmt.Sprintf(", %s left", left.Round(time.Second))
+	}
+	// pad over what is left of the line drawn before
+	pad := p.width - len(line)
+	if pad < 0 {
+		pad = 0
+	}
+	_, _ = fmt.Fprintf(p.w, "\r%s%s", line, strings.Repeat(" ", pad))
+	p.width = len(line)
+	p.drawn = time.Now()
+}
+
+func (p *progress) clear() {
+	_, _ = fmt.Fprintf(p.w, "\r%s\r", strings.Repeat(" ", p.width))
+}
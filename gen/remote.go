@@ -0,0 +1,180 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// remote is a source of files fetched before they are read, a zip or tar
+// archive downloaded from url, or the directory at dir in a git repository
+// cloned at ref. Its files are named after name, as if it were a directory.
+type remote struct {
+	name string
+	// pin is the SHA-256 the archive must have, or the commit ref must
+	// resolve to, if set
+	pin string
+
+	url string
+
+	repo string
+	ref  string
+	dir  string
+}
+
+// parseRemote reads the URL of a remote archive, which can end with
+// #sha256=HEX.
+func parseRemote(spec string) (*remote, error) {
+	raw, pin := splitPin(spec, "sha256")
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%q isn't an HTTP URL", raw)
+	}
+	if !IsArchive(u.Path) {
+		return nil, fmt.Errorf("%q isn't the URL of a zip or tar archive", raw)
+	}
+	return &remote{name: archiveDir(path.Base(u.Path)), pin: pin, url: raw}, nil
+}
+
+// parseGit reads a directory of a git repository written repo@ref:dir, where
+// dir is optional, and which can end with #commit=HEX.
+func parseGit(spec string) (*remote, error) {
+	raw, pin := splitPin(spec, "commit")
+	i := strings.LastIndex(raw, "@")
+	if i <= 0 {
+		return nil, fmt.Errorf("%q isn't written repo@ref:path", raw)
+	}
+	r := &remote{pin: pin, repo: raw[:i], ref: raw[i+1:]}
+	// refs can't hold colons
+	if j := strings.Index(r.ref, ":"); j >= 0 {
+		r.ref, r.dir = r.ref[:j], strings.Trim(path.Clean("/"+r.ref[j+1:]), "/")
+	}
+	if r.ref == "" {
+		return nil, fmt.Errorf("%q has no ref", raw)
+	}
+	if r.name = path.Base(r.dir); r.dir == "" {
+		r.name = strings.TrimSuffix(path.Base(filepath.ToSlash(r.repo)), ".git")
+	}
+	return r, nil
+}
+
+// splitPin splits the pin written #key=HEX at the end of spec, if there is
+// one.
+func splitPin(spec, key string) (string, string) {
+	i := strings.LastIndex(spec, "#"+key+"=")
+	if i < 0 {
+		return spec, ""
+	}
+	return spec[:i], strings.ToLower(spec[i+len(key)+2:])
+}
+
+// fetch downloads or clones the remote to the temporary directory of the
+// generator, and returns the path of the archive or directory holding its
+// files.
+func (g *generator) fetch(ctx context.Context, r *remote) (string, error) {
+	if g.tmpdir == "" {
+		var err error
+		if g.tmpdir, err = ioutil.TempDir("", "gostatic-remote"); err != nil {
+			return "", err
+		}
+	}
+	dir, err := ioutil.TempDir(g.tmpdir, "")
+	if err != nil {
+		return "", err
+	}
+	if r.url != "" {
+		return g.download(ctx, r, dir)
+	}
+	return g.clone(ctx, r, dir)
+}
+
+// download downloads the archive of r in dir, checking it has the SHA-256 it
+// is pinned to.
+func (g *generator) download(ctx context.Context, r *remote, dir string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("couldn't download %q: %s", r.url, resp.Status)
+	}
+	u, _ := url.Parse(r.url)
+	filename := filepath.Join(dir, path.Base(u.Path))
+	file, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, h), resp.Body)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("couldn't download %q: %v", r.url, err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if r.pin == "" {
+		g.logf("Downloaded %q, pin it with #sha256=%s", r.url, sum)
+	} else if sum != r.pin {
+		return "", fmt.Errorf("%q has the SHA-256 %s, not %s", r.url, sum, r.pin)
+	}
+	return filename, nil
+}
+
+// clone clones the repository of r at its ref in dir, checking ref resolves
+// to the commit it is pinned to.
+func (g *generator) clone(ctx context.Context, r *remote, dir string) (string, error) {
+	git := func(args ...string) (string, error) {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	// fetching a single ref works for tags, branches and commits alike
+	if _, err := git("init", "-q"); err != nil {
+		return "", err
+	}
+	if _, err := git("fetch", "-q", "--depth", "1", r.repo, r.ref); err != nil {
+		return "", fmt.Errorf("couldn't fetch %s from %q: %v", r.ref, r.repo, err)
+	}
+	if _, err := git("checkout", "-q", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	commit, err := git("rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if r.pin == "" {
+		g.logf("Cloned %q at %s, pin it with #commit=%s", r.repo, r.ref, commit)
+	} else if !strings.HasPrefix(commit, r.pin) {
+		return "", fmt.Errorf("%s of %q is commit %s, not %s", r.ref, r.repo, commit, r.pin)
+	}
+	dirname := filepath.Join(dir, filepath.FromSlash(r.dir))
+	if fi, err := os.Stat(dirname); err != nil || !fi.IsDir() {
+		return "", fmt.Errorf("%q has no directory %s at %s", r.repo, r.dir, r.ref)
+	}
+	return dirname, nil
+}
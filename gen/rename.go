@@ -0,0 +1,62 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rewrite replaces the prefix Old of an asset name by New.
+type rewrite struct {
+	Old string
+	New string
+}
+
+// renamer renames the assets. Trim is removed from the start of the names
+// first, then the first rule whose Old prefix starts a name applies to it.
+type renamer struct {
+	Trim  string
+	Rules []rewrite
+}
+
+// parseRenamer validates a prefix to trim from the names and a list of rules
+// written `old=>new`.
+func parseRenamer(trimPrefix string, list []string) (renamer, error) {
+	var rn renamer
+	if trimPrefix = strings.TrimSpace(trimPrefix); trimPrefix != "" {
+		// the prefix is written like the names, see namePrefix
+		if trimPrefix = namePrefix(trimPrefix); trimPrefix != "." {
+			rn.Trim = trimPrefix + "/"
+		}
+	}
+	for _, rule := range list {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		i := strings.Index(rule, "=>")
+		if i < 0 {
+			return renamer{}, fmt.Errorf("rule %q isn't written old=>new", rule)
+		}
+		rn.Rules = append(rn.Rules, rewrite{
+			Old: strings.TrimSpace(rule[:i]),
+			New: strings.TrimSpace(rule[i+2:]),
+		})
+	}
+	return rn, nil
+}
+
+// Renames tells if the renamer changes any name.
+func (rn renamer) Renames() bool {
+	return rn.Trim != "" || len(rn.Rules) != 0
+}
+
+// rename returns the new name of an asset.
+func (rn renamer) rename(name string) string {
+	name = strings.TrimPrefix(name, rn.Trim)
+	for _, r := range rn.Rules {
+		if strings.HasPrefix(name, r.Old) {
+			return r.New + name[len(r.Old):]
+		}
+	}
+	return name
+}
@@ -0,0 +1,34 @@
+package gen
+
+import "testing"
+
+// TestRenamerTrim checks that the prefix to trim is written like the names,
+// whichever way it is given.
+func TestRenamerTrim(t *testing.T) {
+	tests := []struct {
+		trimPrefix string
+		name       string
+		want       string
+	}{
+		{trimPrefix: "", name: "assets/a.css", want: "assets/a.css"},
+		{trimPrefix: " . ", name: "assets/a.css", want: "assets/a.css"},
+		{trimPrefix: "assets", name: "assets/a.css", want: "a.css"},
+		{trimPrefix: "./assets/", name: "assets/a.css", want: "a.css"},
+		{trimPrefix: "../assets", name: "assets/a.css", want: "a.css"},
+		{trimPrefix: "/abs/assets", name: "assets/a.css", want: "a.css"},
+		{trimPrefix: "assets//img", name: "assets/img/a.png", want: "a.png"},
+		{trimPrefix: "assets", name: "assetsmore/a.css", want: "assetsmore/a.css"},
+		{trimPrefix: `.\assets\img`, name: "assets/img/a.png", want: "a.png"},
+		{trimPrefix: `C:\abs\assets\`, name: "assets/a.css", want: "a.css"},
+	}
+	for _, tt := range tests {
+		rn, err := parseRenamer(tt.trimPrefix, nil)
+		if err != nil {
+			t.Errorf("parseRenamer(%q): %v", tt.trimPrefix, err)
+			continue
+		}
+		if got := rn.rename(tt.name); got != tt.want {
+			t.Errorf("with %q trimmed, %q is renamed %q, want %q", tt.trimPrefix, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Report describes a generation run, for tools tracking the size of the
+// embedded files over time. It is written as JSON to Options.Report.
+type Report struct {
+	Package string `json:"package"`
+	// ImportPath is the import path of the package, if it is written in a
+	// module.
+	ImportPath string       `json:"import_path,omitempty"`
+	Codec      string       `json:"codec"`
+	Encoding   string       `json:"encoding"`
+	Files      []FileReport `json:"files"`
+	// Skipped are the files that couldn't be read, with OnError "skip".
+	Skipped []string `json:"skipped,omitempty"`
+	// Filtered are the files and directories left out on purpose.
+	Filtered []FilteredReport `json:"filtered,omitempty"`
+	// Size is the total size of the files.
+	Size int64 `json:"size"`
+	// CompressedSize is the total size of the data stored for the files,
+	// counting the files with the same content once.
+	CompressedSize int64 `json:"compressed_size"`
+	// EncodedSize is the total size of the literals holding that data in
+	// the generated code.
+	EncodedSize int64 `json:"encoded_size"`
+	// Ratio is CompressedSize over Size.
+	Ratio float64 `json:"ratio"`
+	// Extensions sum up the files by extension, those storing the most
+	// first.
+	Extensions []ExtensionReport `json:"extensions"`
+}
+
+// FileReport describes a file embedded in a generation run.
+type FileReport struct {
+	// Root names the accessors of the file, like Static for GetStatic.
+	Root string `json:"root"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// CompressedSize is the size of the data stored for the file, the same
+	// as Size if it isn't compressed.
+	CompressedSize int64 `json:"compressed_size"`
+	// EncodedSize is the size of the literal holding that data.
+	EncodedSize int64  `json:"encoded_size"`
+	Hash        string `json:"hash"`
+	ContentType string `json:"content_type"`
+	Compressed  bool   `json:"compressed"`
+	// Duplicate is set when the file has the same content as another, and
+	// doesn't add to the totals.
+	Duplicate bool `json:"duplicate,omitempty"`
+	// BrotliSize is the size of the Brotli variant of the file, if it has
+	// one.
+	BrotliSize int64 `json:"brotli_size,omitempty"`
+	// Gunzipped is set when the file is a gzip stream embedded as the
+	// content it holds, with Gunzip.
+	Gunzipped bool `json:"gunzipped,omitempty"`
+}
+
+// ExtensionReport sums up the files of an extension in a generation run, not
+// counting the duplicates.
+type ExtensionReport struct {
+	// Extension is lower case, with its dot, or empty for the files without
+	// one.
+	Extension      string `json:"extension"`
+	Files          int    `json:"files"`
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressed_size"`
+	EncodedSize    int64  `json:"encoded_size"`
+}
+
+// FilteredReport describes a file or a directory left out on purpose.
+type FilteredReport struct {
+	// Name is the path of the file, ending with a slash for a directory.
+	Name string `json:"name"`
+	// Reason is "excluded", "ignored", "not included" or "symlink".
+	Reason string `json:"reason"`
+}
+
+// filter records a file left out for reason.
+func (r *Report) filter(name string, isDir bool, reason string) {
+	name = filepath.ToSlash(name)
+	if isDir {
+		name += "/"
+	}
+	r.Filtered = append(r.Filtered, FilteredReport{Name: name, Reason: reason})
+}
+
+// add records the entries of a root.
+func (r *Report) add(root string, entries []entry) {
+	for _, e := range entries {
+		f := FileReport{
+			Root:           root,
+			Name:           e.Name,
+			Size:           int64(e.Size),
+			CompressedSize: int64(e.stored),
+			EncodedSize:    e.payload.length + e.blockShare,
+			Hash:           e.Hash,
+			ContentType:    e.ContentType,
+			Compressed:     e.Compressed,
+			Duplicate:      e.Dup != "",
+			Gunzipped:      e.gunzipped,
+			BrotliSize:     int64(e.brotliSize),
+		}
+		r.Files = append(r.Files, f)
+		r.Size += f.Size
+		if !f.Duplicate {
+			r.CompressedSize += f.CompressedSize
+			r.EncodedSize += f.EncodedSize
+		}
+	}
+}
+
+// finish sums up the files once they are all added.
+func (r *Report) finish() {
+	if r.Size > 0 {
+		r.Ratio = float64(r.CompressedSize) / float64(r.Size)
+	}
+	exts := make(map[string]*ExtensionReport)
+	r.Extensions = []ExtensionReport{}
+	for _, f := range r.Files {
+		if f.Duplicate {
+			continue
+		}
+		ext := strings.ToLower(path.Ext(f.Name))
+		e, ok := exts[ext]
+		if !ok {
+			e = &ExtensionReport{Extension: ext}
+			exts[ext] = e
+		}
+		e.Files++
+		e.Size += f.Size
+		e.CompressedSize += f.CompressedSize
+		e.EncodedSize += f.EncodedSize
+	}
+	for _, e := range exts {
+		r.Extensions = append(r.Extensions, *e)
+	}
+	sort.Slice(r.Extensions, func(i, j int) bool {
+		a, b := r.Extensions[i], r.Extensions[j]
+		if a.CompressedSize != b.CompressedSize {
+			return a.CompressedSize > b.CompressedSize
+		}
+		return a.Extension < b.Extension
+	})
+}
+
+// Largest returns the n files storing the most, not counting the duplicates.
+func (r *Report) Largest(n int) []FileReport {
+	var files []FileReport
+	for _, f := range r.Files {
+		if !f.Duplicate {
+			files = append(files, f)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].CompressedSize > files[j].CompressedSize
+	})
+	if len(files) > n {
+		files = files[:n]
+	}
+	return files
+}
+
+// Summary returns the lines of a summary of the sizes: the totals, and the n
+// extensions and the n files storing the most, to see what makes the
+// package large.
+func (r *Report) Summary(n int) []string {
+	lines := []string{fmt.Sprintf("Embedded %d files, %s, stored in %s (%.0f%%), encoded in %s",
+		len(r.Files),
+		humanize.Bytes(uint64(r.Size)),
+		humanize.Bytes(uint64(r.CompressedSize)),
+		100*r.Ratio,
+		humanize.Bytes(uint64(r.EncodedSize)))}
+	if n <= 0 || len(r.Files) == 0 {
+		return lines
+	}
+	lines = append(lines, "Extensions storing the most:")
+	for i, e := range r.Extensions {
+		if i == n {
+			break
+		}
+		ext := e.Extension
+		if ext == "" {
+			ext = "(none)"
+		}
+		lines = append(lines, fmt.Sprintf("  %-12s %6d files %10s -> %10s", ext, e.Files,
+			humanize.Bytes(uint64(e.Size)), humanize.Bytes(uint64(e.CompressedSize))))
+	}
+	lines = append(lines, "Files storing the most:")
+	for _, f := range r.Largest(n) {
+		lines = append(lines, fmt.Sprintf("  %10s -> %10s  %s",
+			humanize.Bytes(uint64(f.Size)), humanize.Bytes(uint64(f.CompressedSize)), f.Name))
+	}
+	return lines
+}
+
+func (r *Report) write(w io.Writer) error {
+	if r.Files == nil {
+		r.Files = []FileReport{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,39 @@
+package gen
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// signedManifest is the manifest of the entries of a root, one line with the
+// hash, size and quoted name of each, signed with SignKey.
+type signedManifest struct {
+	Literal   string
+	Signature string
+}
+
+// sign returns the signed manifest of the entries.
+func (g *generator) sign(entries []entry) *signedManifest {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("%s %d %s\n", e.Hash, e.Size, strconv.Quote(e.Name))
+	}
+	sort.Strings(lines)
+	manifest := strings.Join(lines, "")
+	return &signedManifest{
+		Literal:   backquote(manifest),
+		Signature: hex.EncodeToString(ed25519.Sign(g.SignKey, []byte(manifest))),
+	}
+}
+
+// publicKey returns the hex encoded public key of key, if there is one.
+func publicKey(key ed25519.PrivateKey) string {
+	if key == nil {
+		return ""
+	}
+	return hex.EncodeToString(key.Public().(ed25519.PublicKey))
+}
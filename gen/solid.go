@@ -0,0 +1,113 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+)
+
+// solidBlockSize is the size of the content of the files above which they
+// are put in another block, so that reading a file doesn't decompress too
+// much along with it.
+const solidBlockSize = 1 << 20
+
+// block is the content of small files concatenated and compressed as one
+// stream, as it is written in the generated code.
+type block struct {
+	Var     string
+	Chunked bool
+	payload payload
+	// stored is the size of the compressed content
+	stored int
+	// packed slices the pack holding the content, with the bundle backend,
+	// and Pak is where it is found in the pak, with the pak backend
+	packed string
+	Pak    *span
+}
+
+// Literal returns the encoded content of the block, read back from the
+// spool, or the slice of the pack holding it.
+func (b block) Literal() (string, error) {
+	if b.packed != "" {
+		return b.packed, nil
+	}
+	return b.payload.literal()
+}
+
+// solid tells if a file of size bytes, which would be compressed, goes in a
+// block with Solid.
+func (g *generator) solid(size int) bool {
+	return g.Solid > 0 && size > 0 && int64(size) < g.Solid && !g.hashing && !g.embedding
+}
+
+// solidify puts the content of the entries read with solid in blocks, named
+// after name, and has the entries point at their content in them. The
+// entries with the same content as others must be found already.
+func (g *generator) solidify(entries []entry, name string) ([]block, error) {
+	var blocks []block
+	var content bytes.Buffer
+	var members []int
+	flush := func() error {
+		if len(members) == 0 {
+			return nil
+		}
+		b := block{Var: fmt.Sprintf("%s%d", name, len(blocks)+1)}
+		buf := bytes.NewBuffer(nil)
+		cw, err := g.codec.newWriter(buf, g.level)
+		if err != nil {
+			return err
+		}
+		if _, err = cw.Write(content.Bytes()); err == nil {
+			err = cw.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't compress block %s: %v", b.Var, err)
+		}
+		var literal string
+		literal, b.Chunked = g.literal(buf.Bytes())
+		b.stored = buf.Len()
+		b.payload = payload{length: int64(len(literal))}
+		if !g.dryRun {
+			if b.payload, err = g.spool.add(literal); err != nil {
+				return fmt.Errorf("couldn't spool block %s: %v", b.Var, err)
+			}
+		}
+		g.vlogf("%s\t->\t%s\t%d files in block %s",
+			humanize.Bytes(uint64(content.Len())),
+			humanize.Bytes(uint64(len(literal))),
+			len(members), b.Var)
+		// the files share the size of the block, for the report, adding up
+		// to it
+		size := int64(content.Len())
+		for _, i := range members {
+			e := &entries[i]
+			start, end := int64(e.Offset), int64(e.Offset+e.Size)
+			e.Block = b.Var
+			e.stored = int(end*int64(buf.Len())/size - start*int64(buf.Len())/size)
+			e.blockShare = end*b.payload.length/size - start*b.payload.length/size
+		}
+		blocks = append(blocks, b)
+		content.Reset()
+		members = members[:0]
+		return nil
+	}
+	for i, e := range entries {
+		if e.solid == nil || e.Dup != "" {
+			continue
+		}
+		if content.Len()+len(e.solid) > solidBlockSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		entries[i].Offset = content.Len()
+		_, _ = content.Write(e.solid)
+		entries[i].solid = nil
+		members = append(members, i)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
@@ -0,0 +1,278 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// spool keeps the encoded content of the files on disk between the time they
+// are read and the time the package is written, so that memory usage doesn't
+// grow with the number of files embedded. Its file is only created once a
+// literal is added.
+type spool struct {
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newSpool() *spool {
+	return &spool{}
+}
+
+// add appends a literal to the spool, returning where to find it.
+func (s *spool) add(literal string) (payload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		file, err := ioutil.TempFile("", "gostatic-")
+		if err != nil {
+			return payload{}, err
+		}
+		s.file = file
+	}
+	if _, err := s.file.WriteString(literal); err != nil {
+		return payload{}, err
+	}
+	p := payload{spool: s, offset: s.size, length: int64(len(literal))}
+	s.size += p.length
+	return p, nil
+}
+
+// close removes the spool from the disk.
+func (s *spool) close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if rerr := os.Remove(s.file.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// payload is a literal kept in a spool.
+type payload struct {
+	spool  *spool
+	offset int64
+	length int64
+}
+
+// literal returns a reference to the literal, written in the generated code
+// in its place. The references are replaced by the literals, streamed from
+// the spool, when the code is formatted with format.
+func (p payload) literal() (string, error) {
+	return fmt.Sprintf("\x00%d:%d\x00", p.offset, p.length), nil
+}
+
+// reader reads the literal back from the spool.
+func (p payload) reader() io.Reader {
+	return io.NewSectionReader(p.spool.file, p.offset, p.length)
+}
+
+// maxLine is the length above which gofmt considers that an element of a
+// list doesn't fit on a line, and stops aligning the list around it.
+const maxLine = 1e6
+
+// placeholder stands for a literal of the spool while the code around it is
+// formatted.
+type placeholder struct {
+	payload payload
+	// head is the first line of the literal, if it spans several lines
+	head string
+	// huge is set when the literal makes the element of the list holding
+	// it too long to fit on a line
+	huge bool
+}
+
+// format formats src like gofmt, and writes it to w with the literals it
+// references replaced by the literals. Only the code around them is
+// formatted, with placeholders of the same shape in their place, so that a
+// file holding large literals doesn't need to be held in memory.
+func (s *spool) format(w io.Writer, src []byte) error {
+	parts := bytes.Split(src, []byte{0})
+	// the placeholders are named so that they can't be mistaken for any
+	// other part of the code
+	prefix := "gostaticLiteral"
+	for bytes.Contains(src, []byte(prefix)) {
+		prefix += "_"
+	}
+	var phs []placeholder
+	for i := 1; i < len(parts); i += 2 {
+		var p payload
+		if _, err := fmt.Sscanf(string(parts[i]), "%d:%d", &p.offset, &p.length); err != nil {
+			return fmt.Errorf("invalid literal reference %q", parts[i])
+		}
+		p.spool = s
+		// the literals spanning several lines start with a short type
+		head := make([]byte, 16)
+		if p.length < int64(len(head)) {
+			head = head[:p.length]
+		}
+		if _, err := s.file.ReadAt(head, p.offset); err != nil {
+			return err
+		}
+		ph := placeholder{payload: p}
+		if i := bytes.IndexByte(head, '\n'); i >= 0 {
+			ph.head = string(head[:i])
+		}
+		phs = append(phs, ph)
+	}
+
+	for {
+		skeleton := bytes.NewBuffer(nil)
+		for i, part := range parts {
+			if i%2 == 0 {
+				_, _ = skeleton.Write(part)
+				continue
+			}
+			name := prefix + strconv.Itoa(i/2)
+			switch ph := phs[i/2]; {
+			case ph.head != "":
+				fmt.Fprintf(skeleton, "%s\n%s,\n}", ph.head, name)
+			case ph.huge:
+				fmt.Fprintf(skeleton, "(%s +\n0)", name)
+			default:
+				_, _ = skeleton.WriteString(name)
+			}
+		}
+		formatted, err := format.Source(skeleton.Bytes())
+		if err != nil {
+			return invalidSource(skeleton.Bytes(), err)
+		}
+		if !markHuge(formatted, prefix, phs) {
+			return substitute(w, formatted, prefix, phs)
+		}
+	}
+}
+
+// markHuge finds the elements of the lists in the formatted code which
+// wouldn't fit on a line with their literals, and makes the first
+// placeholder of each span two lines, like they would. It tells if it found
+// any.
+func markHuge(formatted []byte, prefix string, phs []placeholder) bool {
+	found := false
+	for _, line := range strings.Split(string(formatted), "\n") {
+		// the elements holding literals are written "name": {...},
+		trimmed := strings.TrimLeft(line, "\t")
+		key, err := strconv.QuotedPrefix(trimmed)
+		if err != nil || !strings.HasPrefix(trimmed[len(key):], ":") || !strings.HasSuffix(trimmed, ",") {
+			continue
+		}
+		value := strings.TrimLeft(trimmed[len(key)+1:], " ")
+		size := int64(len(key) + len(": ") + len(value) - len(","))
+		first := -1
+		for rest := line; ; {
+			i, start, end := findPlaceholder(rest, prefix)
+			if i < 0 {
+				break
+			}
+			if first < 0 {
+				first = i
+			}
+			size += phs[i].payload.length - int64(end-start)
+			rest = rest[end:]
+		}
+		if first >= 0 && size > maxLine {
+			phs[first].huge, found = true, true
+		}
+	}
+	return found
+}
+
+// substitute writes the formatted code to w, replacing the placeholders
+// with their literals.
+func substitute(w io.Writer, formatted []byte, prefix string, phs []placeholder) error {
+	bw := bufio.NewWriter(w)
+	lines := strings.SplitAfter(string(formatted), "\n")
+	for l := 0; l < len(lines); l++ {
+		line := lines[l]
+		for {
+			i, start, end := findPlaceholder(line, prefix)
+			if i < 0 {
+				break
+			}
+			ph := phs[i]
+			switch {
+			case ph.head != "":
+				// the line holds the placeholder alone, indented one
+				// level deeper than the literal, which is written from
+				// its second line to the one before last
+				indent := strings.TrimSuffix(line[:start], "\t")
+				line = ""
+				from, to := int64(len(ph.head)+1), ph.payload.length-int64(len("\n}"))
+				_, _ = bw.WriteString(indent)
+				middle := io.NewSectionReader(ph.payload.spool.file, ph.payload.offset+from, to-from)
+				if _, err := io.Copy(indenter{w: bw, indent: []byte(indent)}, middle); err != nil {
+					return err
+				}
+				_, _ = bw.WriteString("\n")
+			case ph.huge:
+				// the placeholder is written (name +, with 0) on the
+				// next line
+				_, _ = bw.WriteString(line[:start-len("(")])
+				if _, err := io.Copy(bw, ph.payload.reader()); err != nil {
+					return err
+				}
+				l++
+				line = strings.TrimPrefix(strings.TrimLeft(lines[l], "\t"), "0)")
+			default:
+				_, _ = bw.WriteString(line[:start])
+				if _, err := io.Copy(bw, ph.payload.reader()); err != nil {
+					return err
+				}
+				line = line[end:]
+			}
+		}
+		_, _ = bw.WriteString(line)
+	}
+	return bw.Flush()
+}
+
+// findPlaceholder finds the first placeholder in line, returning its index
+// and where it starts and ends, or -1.
+func findPlaceholder(line, prefix string) (i, start, end int) {
+	start = strings.Index(line, prefix)
+	if start < 0 {
+		return -1, 0, 0
+	}
+	end = start + len(prefix)
+	for end < len(line) && line[end] >= '0' && line[end] <= '9' {
+		end++
+	}
+	i, _ = strconv.Atoi(line[start+len(prefix) : end])
+	return i, start, end
+}
+
+// indenter writes to w, indenting every line after the first with indent.
+type indenter struct {
+	w      io.Writer
+	indent []byte
+}
+
+func (in indenter) Write(p []byte) (int, error) {
+	n := 0
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			m, err := in.w.Write(p)
+			return n + m, err
+		}
+		m, err := in.w.Write(p[:i+1])
+		n += m
+		if err != nil {
+			return n, err
+		}
+		if _, err := in.w.Write(in.indent); err != nil {
+			return n, err
+		}
+		p = p[i+1:]
+	}
+}
@@ -0,0 +1,2730 @@
+package gen
+
+import (
+	"io/ioutil"
+	"text/template"
+)
+
+// parseTemplate reads a custom file template from filename. It is parsed
+// along with the built-in one, so that it can use, or redefine, the
+// templates it defines.
+func parseTemplate(filename string) (*template.Template, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	t, err := filetempl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return t.New("file").Parse(string(src))
+}
+
+var filetempl = template.Must(template.New("file").Funcs(template.FuncMap{
+	"comment": commentSafe,
+}).Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+
+package {{.PkgName}}
+
+import (
+	"bytes"{{if and .Embed (not .Dev)}}
+	"embed"{{end}}{{if and .Pack (not .Dev)}}
+	_ "embed"{{end}}
+	"io/fs"{{if .HTTP}}
+	"net/http"{{end}}{{if .Obfuscate}}
+	"path"{{end}}{{if and .Extract (not .Obfuscate)}}
+	"path/filepath"{{end}}
+	"strings"{{if .Templates}}
+	"sync"
+	{{if .HTMLTemplates}}"html/template"{{else}}"text/template"{{end}}{{end}}
+)
+
+// Get{{.RootName}} will lookup the static assets. It returns a *bytes.Reader
+// and true if found, false otherwise.{{if .Obfuscate}} The names of the static
+// assets are hidden, only the code knowing them finds them.{{else}} The static
+// assets contain exactly the following entries:
+// {{range .Entries}}
+//   {{comment .Name}}{{end}}{{end}}
+//
+// The reader holds a copy of the content, Reader{{.RootName}} doesn't.
+func Get{{.RootName}}(filename string) (*bytes.Reader, bool) {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok {
+		return bytes.NewReader(nil), false
+	}
+	return bytes.NewReader(a.bytes()), true
+}
+
+// Reader{{.RootName}} returns a reader over the content of a static asset,
+// and true if found, false otherwise. The content is never copied: it is
+// immutable, and shared by all the readers, which makes them cheap. A
+// *strings.Reader is an io.ReadSeeker, an io.ReaderAt and an io.WriterTo.
+func Reader{{.RootName}}(filename string) (*strings.Reader, bool) {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok {
+		return strings.NewReader(""), false
+	}
+	return strings.NewReader(a.content()), true
+}
+
+// ReadFile{{.RootName}} returns a copy of the content of a static asset, or
+// an error wrapping fs.ErrNotExist if not found.
+func ReadFile{{.RootName}}(filename string) ([]byte, error) {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: filename, Err: fs.ErrNotExist}
+	}
+	return a.bytes(), nil
+}
+
+// MustGet{{.RootName}} is like ReadFile{{.RootName}}, but panics if the static
+// asset isn't found, for assets that are known to be there like templates
+// loaded at init.
+func MustGet{{.RootName}}(filename string) []byte {
+	data, err := ReadFile{{.RootName}}(filename)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+{{- if not .Obfuscate}}
+
+// List{{.RootName}} will return all the static assets sharing root
+// {{.RootName}}. Each reader holds a copy of the content, see
+// Names{{.RootName}} and Reader{{.RootName}} to avoid it.
+func List{{.RootName}}() map[string]*bytes.Reader {
+	files := files{{.RootName}}()
+	out := make(map[string]*bytes.Reader, len(files))
+	for k, a := range files {
+		out[k] = bytes.NewReader(a.bytes())
+	}
+	return out
+}
+
+// Names{{.RootName}} returns the sorted names of all the static assets
+// sharing root {{.RootName}}, without reading their content.
+func Names{{.RootName}}() []string {
+	return names(files{{.RootName}}())
+}
+{{- end}}
+
+// Stat{{.RootName}} returns the information recorded about a static asset
+// when it was embedded, and true if found, false otherwise.
+func Stat{{.RootName}}(filename string) (fs.FileInfo, bool) {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok {
+		return nil, false
+	}
+{{- if .Obfuscate}}
+	// the asset only knows the hash of its name
+	fi := a.info()
+	fi.name = path.Base(filename)
+	return fi, true
+{{- else}}
+	return a.info(), true
+{{- end}}
+}
+
+// Size{{.RootName}} returns the size in bytes of a static asset, once
+// decompressed, and true if found, false otherwise. Its content isn't read.
+func Size{{.RootName}}(filename string) (int64, bool) {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok {
+		return 0, false
+	}
+	return a.size, true
+}
+
+// TotalSize{{.RootName}} returns the size in bytes of all the static assets
+// sharing root {{.RootName}}, once decompressed. Their content isn't read.
+func TotalSize{{.RootName}}() int64 {
+	var total int64
+	for _, a := range files{{.RootName}}() {
+		total += a.size
+	}
+	return total
+}
+
+// Hash{{.RootName}} returns the hex encoded SHA-256 of the content of a static
+// asset, and true if found, false otherwise. It is computed when the asset is
+// embedded, and is suitable as an ETag.
+func Hash{{.RootName}}(filename string) (string, bool) {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok {
+		return "", false
+	}
+	return a.hash, true
+}
+{{- if not .Obfuscate}}
+
+// Hashes{{.RootName}} returns the hex encoded SHA-256 of the content of all
+// the static assets sharing root {{.RootName}}, keyed by name.
+func Hashes{{.RootName}}() map[string]string {
+	files := files{{.RootName}}()
+	out := make(map[string]string, len(files))
+	for k, a := range files {
+		out[k] = a.hash
+	}
+	return out
+}
+{{- end}}
+
+// ContentType{{.RootName}} returns the MIME type of a static asset, or an
+// empty string if not found. It is resolved when the asset is embedded, from
+// the extension of its name or else from its content.
+func ContentType{{.RootName}}(filename string) string {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok {
+		return ""
+	}
+	return a.contentType
+}
+{{- if not .Obfuscate}}
+
+// ContentTypes{{.RootName}} returns the MIME type of all the static assets
+// sharing root {{.RootName}}, keyed by name.
+func ContentTypes{{.RootName}}() map[string]string {
+	files := files{{.RootName}}()
+	out := make(map[string]string, len(files))
+	for k, a := range files {
+		out[k] = a.contentType
+	}
+	return out
+}
+
+// ReadDir{{.RootName}} returns the entries of the directory called name among
+// the static assets sharing root {{.RootName}}, sorted by filename. The root
+// directory is ".".
+func ReadDir{{.RootName}}(name string) ([]fs.DirEntry, error) {
+	return readDir(files{{.RootName}}(), tree{{.RootName}}(), name)
+}
+
+// Walk{{.RootName}} walks the static assets sharing root {{.RootName}} like
+// fs.WalkDir, calling fn for each file and directory, starting with the root
+// directory ".".
+func Walk{{.RootName}}(fn fs.WalkDirFunc) error {
+	return walk(files{{.RootName}}(), tree{{.RootName}}(), fn)
+}
+
+// Glob{{.RootName}} returns the sorted names of the static assets sharing root
+// {{.RootName}} matching pattern, with the syntax of path.Match. It returns
+// nil if the pattern is malformed.
+func Glob{{.RootName}}(pattern string) []string {
+	return glob(files{{.RootName}}(), pattern)
+}
+{{- end}}
+{{- if .Templates}}
+
+// templates{{.RootName}} holds the templates parsed by Templates{{.RootName}}.
+var templates{{.RootName}} struct {
+	once sync.Once
+	set  *template.Template
+	err  error
+}
+
+// Templates{{.RootName}} parses the static assets sharing root {{.RootName}}
+// that are templates into a set with {{if .HTMLTemplates}}html/template{{else}}text/template{{end}}, the first time it
+// is called. Each template is named after its asset, like
+// {{printf "%q" (index .Templates 0).Name}}, to be run with ExecuteTemplate.
+func Templates{{.RootName}}() (*template.Template, error) {
+	t := &templates{{.RootName}}
+	t.once.Do(func() {
+		set := template.New("")
+		for _, f := range []struct{ name, asset string }{ {{- range .Templates}}
+			{ {{- printf "%q" .Name}}, {{printf "%q" .Asset}}},{{end}}
+		} {
+			a, ok := lookup{{.RootName}}(f.asset)
+			if !ok {
+				continue
+			}
+			if _, err := set.New(f.name).Parse(a.content()); err != nil {
+				t.err = err
+				return
+			}
+		}
+		t.set = set
+	})
+	return t.set, t.err
+}
+{{- end}}
+{{- if .Release}}
+
+// Release{{.RootName}} drops the decompressed content of a static asset, kept
+// since it was first needed, to free memory. It is decompressed again the
+// next time it is needed.{{if .Blocks}} The assets compressed together in
+// blocks are only freed by Purge{{.RootName}}, along with their block.{{end}}
+func Release{{.RootName}}(filename string) {
+	if a, ok := lookup{{.RootName}}(filename); ok {
+		a.release()
+	}
+}
+
+// Purge{{.RootName}} drops the decompressed content of all the static assets
+// sharing root {{.RootName}}, like Release{{.RootName}} does for one.
+func Purge{{.RootName}}() {
+	releaseAll(files{{.RootName}}())
+}
+{{- end}}
+{{- if .Precompressed}}
+
+// GetCompressed{{.RootName}} returns a copy of the gzip stream of a static
+// asset as it is embedded, to pass it on without decompressing it, and true
+// if found and stored compressed, false otherwise. The assets that aren't,
+// like images, are only found by Get{{.RootName}}.
+func GetCompressed{{.RootName}}(filename string) ([]byte, bool) {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok || !a.compressed {
+		return nil, false
+	}
+	return append([]byte(nil), a.gzipped()...), true
+}
+{{- end}}
+{{- if .Locales}}
+
+// locales{{.RootName}} are the names of the catalogs, in the order of their
+// language tags in localeMatcher{{.RootName}}.
+var locales{{.RootName}} = []string{ {{- range $i, $l := .Locales}}{{if $i}}, {{end}}{{printf "%q" $l.Asset}}{{end}}}
+
+// localeMatcher{{.RootName}} finds the catalog best matching language tags.
+var localeMatcher{{.RootName}} = newLocaleMatcher({{range $i, $l := .Locales}}{{if $i}}, {{end}}{{printf "%q" $l.Tag}}{{end}})
+
+// Locales{{.RootName}} returns the language tags of the catalogs among the
+// static assets sharing root {{.RootName}}, sorted.
+func Locales{{.RootName}}() []string {
+	return []string{ {{- range $i, $l := .Locales}}{{if $i}}, {{end}}{{printf "%q" $l.Tag}}{{end}}}
+}
+
+// Locale{{.RootName}} returns the content of the catalog best matching tag, a
+// language tag like "fr-CA" or the value of an Accept-Language header like
+// "fr-CA, fr;q=0.9, en;q=0.8", and true if found, false otherwise. Tags are
+// matched like golang.org/x/text/language does, so that "fr-CA" falls back
+// on "fr", "pt" finds "pt-BR" and "zh-TW" finds "zh-Hant"{{if .DefaultLocale}}, and the
+// catalog of {{printf "%q" .DefaultLocale}} is returned when none matches{{end}}.
+func Locale{{.RootName}}(tag string) ([]byte, bool) {
+	i, ok := matchLocale(localeMatcher{{.RootName}}, tag)
+	if !ok {
+{{- range $i, $l := .Locales}}{{if $l.Default}}
+		i = {{$i}}
+{{- end}}{{end}}
+{{- if not .DefaultLocale}}
+		return nil, false
+{{- end}}
+	}
+	a, ok := lookup{{.RootName}}(locales{{.RootName}}[i])
+	if !ok {
+		return nil, false
+	}
+	return a.bytes(), true
+}
+{{- end}}
+{{- if .Integrity}}
+
+// Integrity{{.RootName}} returns the Subresource Integrity value of a static
+// asset, like "sha384-...", for the integrity attribute of the element
+// loading it. It is only computed for scripts and style sheets, and is empty
+// for the other assets or if not found.
+func Integrity{{.RootName}}(filename string) string {
+	a, ok := lookup{{.RootName}}(filename)
+	if !ok {
+		return ""
+	}
+	return a.integrity
+}
+{{- end}}
+{{- if .Extract}}
+{{- if not .Obfuscate}}
+
+// ExtractToDir{{.RootName}} writes the static assets sharing root
+// {{.RootName}} to dir, under their names, with the modes they were recorded
+// with. Each file is written to a temporary file renamed over it, so that
+// programs running it never see it half written. It refuses names that would
+// be written outside of dir, returning an error wrapping fs.ErrInvalid.
+func ExtractToDir{{.RootName}}(dir string) error {
+	for name, a := range files{{.RootName}}() {
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(dir, dest); !fs.ValidPath(name) || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return &fs.PathError{Op: "extract", Path: name, Err: fs.ErrInvalid}
+		}
+		if err := a.extract(dest, a.mode.Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+{{- end}}
+
+// ExtractFile{{.RootName}} writes the static asset called name to the file
+// dest with the mode perm, or the one it was recorded with if perm is 0,
+// through a temporary file renamed over dest. It returns an error wrapping
+// fs.ErrNotExist if the asset isn't found.
+func ExtractFile{{.RootName}}(name, dest string, perm fs.FileMode) error {
+	a, ok := lookup{{.RootName}}(name)
+	if !ok {
+		return &fs.PathError{Op: "extract", Path: name, Err: fs.ErrNotExist}
+	}
+	if perm == 0 {
+		perm = a.mode.Perm()
+	}
+	return a.extract(dest, perm)
+}
+{{- end}}
+{{- if .Fingerprint}}
+
+// Manifest{{.RootName}} maps the names the static assets sharing root
+// {{.RootName}} had before being fingerprinted to their names. HTML pages
+// aren't fingerprinted, and aren't in the manifest.
+func Manifest{{.RootName}}() map[string]string {
+	files := files{{.RootName}}()
+	out := make(map[string]string, len(files))
+	for k, a := range files {
+		if a.original != "" {
+			out[a.original] = k
+		}
+	}
+	return out
+}
+{{- end}}
+{{if .HTTP}}
+// HTTP{{.RootName}} returns an http.FileSystem serving the static assets
+// sharing root {{.RootName}}, suitable for use with http.FileServer. Names
+// are looked up like with Get{{.RootName}}, ignoring the leading slash.
+func HTTP{{.RootName}}() http.FileSystem {
+	return FileSystem{FS{{.RootName}}()}
+}
+
+// Handler{{.RootName}} returns an http.Handler serving the static assets
+// sharing root {{.RootName}} at the path of each request, once trimmed of
+// prefix. Responses carry the Content-Type, ETag, Last-Modified and
+// CacheControl headers, and conditional and range requests are honored.
+func Handler{{.RootName}}(prefix string) http.Handler {
+	return handler{fsys: FS{{.RootName}}(), prefix: prefix}
+}
+
+// SPAHandler{{.RootName}} is like Handler{{.RootName}}, for single-page apps:
+// the static asset named fallback, like "index.html", is served in place of
+// the ones not found, instead of a 404.
+func SPAHandler{{.RootName}}(prefix, fallback string) http.Handler {
+	return handler{fsys: FS{{.RootName}}(), prefix: prefix, fallback: fallback}
+}
+{{- if .Overrides}}
+
+// OverrideHandler{{.RootName}} is like Handler{{.RootName}}, but serves the
+// files found in the directory dir on disk in place of the static assets of
+// the same names, so that one can be patched in production without
+// rebuilding. They are read on each request, and the static assets are
+// served when dir doesn't hold them, or when dir is empty.
+func OverrideHandler{{.RootName}}(prefix, dir string) http.Handler {
+	return handler{fsys: FS{{.RootName}}(), prefix: prefix, overrides: dir}
+}
+{{- end}}
+{{end}}{{if not .Obfuscate}}
+// FS{{.RootName}} returns an FS holding the static assets sharing root
+// {{.RootName}}.{{if .IOFS}} It implements fs.FS, fs.ReadDirFS, fs.ReadFileFS,
+// fs.StatFS and fs.GlobFS.{{end}}
+func FS{{.RootName}}() FS {
+	return FS{lookup: lookup{{.RootName}}, files: files{{.RootName}}, tree: tree{{.RootName}}}
+}
+{{end}}{{if not .Dev}}{{template "data" .}}{{end}}
+{{- define "data"}}
+func lookup{{.RootName}}(filename string) (*asset, bool) {
+{{- if .NFCLookups}}
+	filename = normalized(filename)
+{{- end}}
+	a, ok := assets{{.RootName}}[{{if .Obfuscate}}obfuscated(filename){{else}}filename{{end}}]
+{{- if .Folded}}
+	if !ok {
+		// the names are matched ignoring case then
+		a, ok = assets{{.RootName}}[folded{{.RootName}}[{{if .Obfuscate}}obfuscated(folded(filename)){{else}}folded(filename){{end}}]]
+	}
+{{- end}}
+	return a, ok
+}
+
+func files{{.RootName}}() map[string]*asset {
+	return assets{{.RootName}}
+}
+{{- if not .Obfuscate}}
+
+func tree{{.RootName}}() map[string][]string {
+	return dirs{{.RootName}}
+}
+{{- end}}
+{{if .Embed}}{{range .Entries}}
+//go:embed {{printf "%q" .File}}{{end}}
+var embed{{.RootName}} embed.FS
+{{end}}{{with .Pak}}
+// {{.Var}} holds the stored content of the assets, read from {{.File}} on
+// first access.
+var {{.Var}} = &pak{file: {{printf "%q" .File}}, size: {{.Size}}, sum: {{printf "%q" .Sum}}}
+{{end}}
+{{- with .Pack}}
+// {{.Var}} holds the stored content of the assets, which slice it.
+//go:embed {{printf "%q" .File}}
+var {{.Var}} string
+{{end}}
+{{- if .Split}}
+// assets{{.RootName}} is split across files, to keep them small.
+var assets{{.RootName}} = joinAssets({{range $i, $p := .Parts}}{{if $i}}, {{end}}{{$p.Var}}{{end}})
+{{else}}{{template "map" index .Parts 0}}{{end}}
+{{- if not .Obfuscate}}
+// dirs{{.RootName}} lists the files and directories of each directory.
+var dirs{{.RootName}} = map[string][]string{ {{- range .Tree}}
+	{{printf "%q" .Name}}: { {{- range $i, $c := .Children}}{{if $i}}, {{end}}{{printf "%q" $c}}{{end}}},{{end}}
+}
+{{- end}}
+{{- if .Folded}}
+
+// folded{{.RootName}} maps the names of the assets in lower case to their
+// names.
+var folded{{.RootName}} = map[string]string{ {{- range .Folded}}
+	{{printf "%q" .Folded}}: {{printf "%q" .Name}},{{end}}
+}
+{{- end}}
+{{range .Blocks}}
+// {{.Var}} holds small assets compressed together.
+var {{.Var}} = &block{ {{- if .Pak}}pakSpan: span{ {{- .Pak.Var}}, {{.Pak.Offset}}, {{.Pak.Length}}}{{else}}{{if .Chunked}}chunks{{else}}encoded{{end}}: {{.Literal}}{{end}}}
+{{end}}
+{{- with .Signed}}
+// manifest{{$.RootName}} lists the hash, size and name of each static asset,
+// signed when they were embedded.
+const manifest{{$.RootName}} = {{.Literal}}
+
+// signature{{$.RootName}} is the hex encoded ed25519 signature of
+// manifest{{$.RootName}}.
+const signature{{$.RootName}} = {{printf "%q" .Signature}}
+
+func init() {
+	verifyManifest({{printf "%q" $.RootName}}, manifest{{$.RootName}}, signature{{$.RootName}}, assets{{$.RootName}})
+}
+{{end}}
+{{- if not .Lazy}}
+func init() {
+	for _, a := range assets{{.RootName}} {
+		a.content()
+	}
+}
+{{end}}
+{{- end}}
+{{- define "map"}}
+var {{.Var}} = map[string]*asset{ {{- range .Entries}}
+	{{printf "%q" .Name}}: {{if .Shared}}{{.Shared}}{{else}}{ {{- template "fields" .}}}{{end}},{{end}}
+}
+{{range .Entries}}{{if .Shared}}
+// {{.Shared}} has the same content as other assets.
+var {{.Shared}} = &asset{ {{- template "fields" .}}}
+{{end}}{{end}}
+{{- end}}
+{{- define "fields"}}name: {{printf "%q" .Name}}, size: {{.Size}}, mode: {{printf "%#o" .Mode}}, modTime: {{.ModTime}}, hash: {{printf "%q" .Hash}}, contentType: {{printf "%q" .ContentType}}, compressed: {{.Compressed}}, {{if .Original}}original: {{printf "%q" .Original}}, {{end}}{{if .Integrity}}integrity: {{printf "%q" .Integrity}}, {{end}}{{if .FS}}fsys: &{{.FS}}, file: {{printf "%q" .File}}{{else if .Dup}}dup: {{.Dup}}{{else}}{{if .Block}}block: {{.Block}}, offset: {{.Offset}}{{else if .Pak}}pakSpan: span{ {{- .Pak.Var}}, {{.Pak.Offset}}, {{.Pak.Length}}}{{else}}{{if .Chunked}}chunks{{else}}encoded{{end}}: {{.Literal}}{{end}}{{if .BrotliPak}}, brSpan: span{ {{- .BrotliPak.Var}}, {{.BrotliPak.Offset}}, {{.BrotliPak.Length}}}{{else if .Brotli}}, {{if .BrotliChunked}}brChunks{{else}}br{{end}}: {{.BrotliLiteral}}{{end}}{{end}}
+{{- end}}
+{{- define "part"}}// GENERATED FILE: Do not edit, all changes will be lost.
+{{if .Dev}}
+//go:build !dev
+{{end}}
+package {{.PkgName}}
+{{template "map" .Part}}
+{{- end}}
+{{- define "embed"}}// GENERATED FILE: Do not edit, all changes will be lost.
+
+//go:build !dev
+
+package {{.PkgName}}
+{{if .Embed}}
+import "embed"
+{{else if .Pack}}
+import _ "embed"
+{{end}}{{template "data" .}}
+{{- end}}
+{{- define "dev"}}// GENERATED FILE: Do not edit, all changes will be lost.
+
+//go:build dev
+
+package {{.PkgName}}
+
+// dev{{.RootName}} is read in place of the static assets sharing root
+// {{.RootName}} in dev builds.
+var dev{{.RootName}} = devRoots{ {{- range .DevRoots}}
+	{
+		prefix: {{printf "%q" .Prefix}},
+		dir:    devDir({{printf "%q" .Dir}}),
+{{- if $.Renamer.Renames}}
+		renamer: renamer{
+			trim: {{printf "%q" $.Renamer.Trim}},
+			rules: []rewrite{ {{- range $.Renamer.Rules}}
+				{old: {{printf "%q" .Old}}, new: {{printf "%q" .New}}},{{end}}
+			},
+		},
+{{- end}}
+{{- if $.Fingerprint}}
+		fingerprint: true,
+{{- end}}
+{{- if $.FollowSymlinks}}
+		symlinks: true,
+{{- end}}
+{{- if $.Gunzip}}
+		gunzip: true,
+{{- end}}
+	},{{end}}
+}
+
+func lookup{{.RootName}}(filename string) (*asset, bool) {
+	return dev{{.RootName}}.{{if .Folded}}lookupFold{{else}}lookup{{end}}(filename)
+}
+
+func files{{.RootName}}() map[string]*asset {
+	return dev{{.RootName}}.files()
+}
+
+func tree{{.RootName}}() map[string][]string {
+	return devTree(dev{{.RootName}}.files())
+}
+{{end}}`))
+
+var devtempl = template.Must(template.New("dev").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+{{if not .IOFS}}
+//go:build dev
+{{end}}
+package {{.PkgName}}
+
+import ({{if .Gunzip}}
+	"bytes"
+	"compress/gzip"{{end}}
+	"crypto/sha256"{{if .Integrity}}
+	"crypto/sha512"
+	"encoding/base64"{{end}}
+	"encoding/hex"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// devDir returns the path of the directory found at dir, relative to the
+// package unless it is absolute. The package is found from the path of this
+// file, recorded when it is compiled, so dev builds must be built from the
+// sources of the package, without -trimpath.
+func devDir(dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	_, filename, _, ok := runtime.Caller(0)
+	if !ok {
+		return filepath.FromSlash(dir)
+	}
+	return filepath.Join(filepath.Dir(filename), filepath.FromSlash(dir))
+}
+
+// devRoots reads directories straight from disk, looking up a name in each of
+// them in turn.
+type devRoots []devRoot
+
+func (rs devRoots) lookup(name string) (*asset, bool) {
+	for _, r := range rs {
+		if a, ok := r.lookup(name); ok {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+{{- if .IgnoreCase}}
+// lookupFold looks name up, ignoring case if it isn't found as is.
+func (rs devRoots) lookupFold(name string) (*asset, bool) {
+	if a, ok := rs.lookup(name); ok {
+		return a, true
+	}
+	for other, a := range rs.files() {
+		if folded(other) == folded(name) {
+			return a, true
+		}
+	}
+	return nil, false
+}
+{{end}}
+func (rs devRoots) files() map[string]*asset {
+	files := make(map[string]*asset)
+	for _, r := range rs {
+		for name, a := range r.files() {
+			files[name] = a
+		}
+	}
+	return files
+}
+
+// devRoot reads a directory straight from disk, so that dev builds see
+// changes without regenerating the package. All the files of the directory
+// are read, whether they were embedded or not. It can also be a file given
+// on its own, read alone.
+type devRoot struct {
+	// prefix starts the names of the files, like it does for the embedded
+	// files.
+	prefix  string
+	dir     string
+	renamer renamer
+	// fingerprint adds the start of the hash of each file but the HTML
+	// pages to its name
+	fingerprint bool
+	// symlinks are followed, unless they lead back to a directory holding
+	// them, otherwise they are skipped
+	symlinks bool
+{{- if .Gunzip}}
+	// gunzip reads the files ending with .gz as the content of their gzip
+	// stream, under their name without .gz
+	gunzip bool
+{{- end}}
+}
+
+func (r devRoot) lookup(name string) (*asset, bool) {
+	if r.renamer.renames() || r.fingerprint{{if .Gunzip}} || r.gunzip{{end}} {
+		// the file of a renamed asset can't be told from its name
+		a, ok := r.files()[name]
+		return a, ok
+	}
+	if path.Clean(name) != name {
+		return nil, false
+	}
+	rel := name
+	if name == r.prefix {
+		// the root is a file given on its own
+		rel = "."
+	} else if r.prefix != "." {
+		if !strings.HasPrefix(name, r.prefix+"/") {
+			return nil, false
+		}
+		rel = name[len(r.prefix)+1:]
+	}
+	filename := filepath.Join(r.dir, filepath.FromSlash(rel))
+	if !r.symlinks {
+		// the file isn't found through symlinks, like when it was embedded
+		dir, err := filepath.EvalSymlinks(r.dir)
+		if err != nil {
+			return nil, false
+		}
+		if real, err := filepath.EvalSymlinks(filename); err != nil || real != filepath.Join(dir, filepath.FromSlash(rel)) {
+			return nil, false
+		}
+	}
+	fi, err := os.Stat(filename)
+	if err != nil || fi.IsDir() {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, false
+	}
+	return devAsset(name, fi, data), true
+}
+
+func (r devRoot) files() map[string]*asset {
+	files := make(map[string]*asset)
+	root, err := os.Stat(r.dir)
+	if err != nil {
+		return files
+	}
+	add := func(filename, rel string, fi os.FileInfo) {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return
+		}
+		name := r.renamer.rename(path.Join(r.prefix, rel))
+{{- if .Gunzip}}
+		if r.gunzip && strings.HasSuffix(fi.Name(), ".gz") && fi.Name() != ".gz" {
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return
+			}
+			if data, err = ioutil.ReadAll(zr); err != nil {
+				return
+			}
+			name = strings.TrimSuffix(name, ".gz")
+		}
+{{- end}}
+		a := devAsset(name, fi, data)
+		if ext := strings.ToLower(path.Ext(a.name)); r.fingerprint && ext != ".html" && ext != ".htm" {
+			a.original, a.name = a.name, fingerprint(a.name, a.hash)
+		}
+		files[a.name] = a
+	}
+	if !root.IsDir() {
+		// a file given on its own
+		add(r.dir, ".", root)
+		return files
+	}
+	r.walk(r.dir, ".", []os.FileInfo{root}, add)
+	return files
+}
+
+// walk calls fn with the files of the directory called dirname, found at rel
+// in the root, and held by the directories parents.
+func (r devRoot) walk(dirname, rel string, parents []os.FileInfo, fn func(filename, rel string, fi os.FileInfo)) {
+	infos, err := ioutil.ReadDir(dirname)
+	if err != nil {
+		return
+	}
+	for _, fi := range infos {
+		filename, name := filepath.Join(dirname, fi.Name()), path.Join(rel, fi.Name())
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if !r.symlinks {
+				continue
+			}
+			if fi, err = os.Stat(filename); err != nil {
+				continue
+			}
+		}
+		if !fi.IsDir() {
+			fn(filename, name, fi)
+			continue
+		}
+		cycle := false
+		for _, parent := range parents {
+			cycle = cycle || os.SameFile(parent, fi)
+		}
+		if !cycle {
+			r.walk(filename, name, append(parents, fi), fn)
+		}
+	}
+}
+
+// devTree derives the directories holding files, like they are recorded for
+// the embedded assets.
+func devTree(files map[string]*asset) map[string][]string {
+	children := map[string]map[string]bool{".": {}}
+	for name := range files {
+		for ; name != "." && name != "/"; name = path.Dir(name) {
+			parent := path.Dir(name)
+			if children[parent] == nil {
+				children[parent] = make(map[string]bool)
+			}
+			children[parent][path.Base(name)] = true
+		}
+	}
+	tree := make(map[string][]string, len(children))
+	for name, names := range children {
+		tree[name] = make([]string, 0, len(names))
+		for child := range names {
+			tree[name] = append(tree[name], child)
+		}
+		sort.Strings(tree[name])
+	}
+	return tree
+}
+
+// renamer renames the assets like they were when embedded. trim is removed
+// from the start of the names first, then the first rule whose old prefix
+// starts a name replaces that prefix by new.
+type renamer struct {
+	trim  string
+	rules []rewrite
+}
+
+type rewrite struct {
+	old, new string
+}
+
+func (rn renamer) renames() bool {
+	return rn.trim != "" || len(rn.rules) != 0
+}
+
+func (rn renamer) rename(name string) string {
+	name = strings.TrimPrefix(name, rn.trim)
+	for _, r := range rn.rules {
+		if strings.HasPrefix(name, r.old) {
+			return r.new + name[len(r.old):]
+		}
+	}
+	return name
+}
+
+// fingerprint inserts the start of hash in name, before its extension.
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	if ext == path.Base(name) {
+		ext = ""
+	}
+	return name[:len(name)-len(ext)] + "." + hash[:8] + ext
+}
+
+// devAsset returns an asset holding data, which needs no decoding.
+func devAsset(name string, fi os.FileInfo, data []byte) *asset {
+	a := &asset{
+		name:    name,
+		size:    int64(len(data)),
+		mode:    fi.Mode(),
+		modTime: fi.ModTime().UnixNano(),
+	}
+	sum := sha256.Sum256(data)
+	a.hash = hex.EncodeToString(sum[:])
+	if a.contentType = mime.TypeByExtension(path.Ext(name)); a.contentType == "" {
+		a.contentType = http.DetectContentType(data)
+	}
+{{- if .Integrity}}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".js", ".mjs", ".css":
+		sum := sha512.Sum384(data)
+		a.integrity = "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	}
+{{- end}}
+{{- if .Release}}
+	a.cache.set(string(data))
+{{- else}}
+	a.once.Do(func() { a.data = string(data) })
+{{- end}}
+	return a
+}
+`))
+
+var commontempl = template.Must(template.New("common").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+
+package {{.PkgName}}
+
+import ({{if .Codec.Import}}{{if not .Codec.External}}
+	"bytes"
+	"{{.Codec.Import}}"{{end}}{{end}}{{if .MemoryBudget}}
+	"container/list"{{end}}{{if .Encrypt}}
+	"crypto/aes"
+	"crypto/cipher"{{end}}{{if .Signed}}
+	"crypto/ed25519"{{end}}{{if or .Pak .Obfuscate .Signed (eq .Extract "changed")}}
+	"crypto/sha256"{{end}}{{if eq .Encoding.Name "base64"}}
+	"encoding/base64"{{end}}{{if or .Pak .Encrypt .Obfuscate .Signed (eq .Extract "changed")}}
+	"encoding/hex"{{end}}{{if .Embed}}
+	"embed"{{end}}{{if or .Pak .Encrypt}}
+	"fmt"{{end}}{{if or .Pak (ne .Extract "none")}}
+	"io"{{end}}
+	"io/fs"{{if or (and .Codec.Import (not .Codec.External)) (ne .Extract "none")}}
+	"io/ioutil"{{end}}{{if or .Codec.Import (eq .Encoding.Name "base64") .Embed .Pak .Encrypt .Signed}}
+	"log"{{end}}{{if .Pak}}
+	"net/http"{{end}}{{if or .Pak .Encrypt (ne .Extract "none")}}
+	"os"{{end}}
+	"path"{{if or .Pak (ne .Extract "none")}}
+	"path/filepath"{{end}}
+	"sort"{{if .Signed}}
+	"strconv"{{end}}{{if or (eq .Encoding.Name "string") .Pak .Encrypt .Signed .IgnoreCase .Locales}}
+	"strings"{{end}}
+	"sync"{{if .Release}}
+	"sync/atomic"{{end}}
+	"time"{{if or .Codec.External .NFCLookups .Locales}}
+{{end}}{{if .Codec.External}}
+	"{{.Codec.Import}}"{{end}}{{if .Locales}}
+	"golang.org/x/text/language"{{end}}{{if .NFCLookups}}
+	"golang.org/x/text/unicode/norm"{{end}}
+)
+{{- if eq .Codec.Name "zstd"}}
+
+// zstdDecoder is shared by all the assets, as DecodeAll is safe for
+// concurrent use.
+var zstdDecoder, _ = zstd.NewReader(nil)
+{{- end}}
+
+// asset is a static asset, stored with the {{.Codec.Name}} codec and the
+// {{.Encoding.Name}} encoding. Its content is decoded and decompressed once,
+// the first time it is needed, and kept in a string{{if .Release}} until released{{end}}.{{if .Encrypt}} It is
+// stored encrypted with AES-GCM, and decrypted along.{{end}}
+type asset struct {
+	name        string
+	size        int64
+	mode        fs.FileMode
+	modTime     int64 // in nanoseconds since the epoch, 0 if unknown
+	hash        string
+	contentType string
+	compressed  bool
+	encoded     {{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}
+	// chunks hold the encoded content in pieces instead, when it is too
+	// large for a single literal
+	chunks []{{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}
+{{- if .Brotli}}
+	// br holds the encoded Brotli variant of the content, if any, in
+	// brChunks when it is too large for a single literal
+	br       {{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}
+	brChunks []{{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}
+{{- end}}
+	// dup holds the content instead, when it is the same for both
+	dup *asset
+{{- if .Solid}}
+	// block holds the content instead at offset, along with other small
+	// assets
+	block  *block
+	offset int
+{{- end}}
+	// original is the name of the asset before it was fingerprinted
+	original string
+{{- if .Embed}}
+	// fsys holds the content at file instead, with the embed backend
+	fsys *embed.FS
+	file string
+{{- end}}
+{{- if .Pak}}
+	// pakSpan holds the stored content instead, and brSpan its Brotli
+	// variant, with the pak backend
+	pakSpan span
+	brSpan  span
+{{- end}}
+{{- if .Integrity}}
+	// integrity is the Subresource Integrity value of scripts and style
+	// sheets
+	integrity string
+{{- end}}
+
+{{- if .Release}}
+
+	cache cached
+	// data holds the content while it is loaded into the cache
+	data string
+{{- if .MemoryBudget}}
+	// used is the element of the asset in lru while its content is loaded
+	used *list.Element
+{{- end}}
+{{- else}}
+
+	once sync.Once
+	data string
+{{- end}}
+{{- if .Precompressed}}
+
+	gzOnce sync.Once
+	gz     []byte
+{{- end}}
+{{- if .Brotli}}
+
+	brOnce sync.Once
+	brData []byte
+{{- end}}
+}
+
+// info returns the information recorded about the asset when it was
+// embedded.
+func (a *asset) info() fileInfo {
+	fi := fileInfo{name: path.Base(a.name), size: a.size, mode: a.mode}
+	if a.modTime != 0 {
+		fi.modTime = time.Unix(0, a.modTime)
+	}
+	return fi
+}
+
+// decoded returns the content of the asset as it is embedded, which is still
+// compressed if the asset is.
+func (a *asset) decoded() []byte {
+{{- if .Encrypt}}
+	return decrypt(a.name, a.sealed())
+}
+
+// sealed returns the encrypted content of the asset, as it is embedded.
+func (a *asset) sealed() []byte {
+{{- end}}
+{{- if .Pak}}
+	if a.pakSpan.pak != nil {
+		return a.pakSpan.read(a.name)
+	}
+{{- end}}
+	return a.join(a.encoded, a.chunks)
+}
+
+// join decodes an encoded literal of the asset, or the chunks of one.
+func (a *asset) join(encoded {{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}, chunks []{{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}) []byte {
+	if chunks == nil {
+		return a.decode(encoded)
+	}
+	var data []byte
+	for _, chunk := range chunks {
+		data = append(data, a.decode(chunk)...)
+	}
+	return data
+}
+
+// decode turns an encoded literal of the asset back into bytes.
+func (a *asset) decode(encoded {{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}) []byte {
+{{- if eq .Encoding.Name "base64"}}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Panicf("Couldn't decode base64 data for %q: %v", a.name, err)
+	}
+	return data
+{{- else if eq .Encoding.Name "base256"}}
+	data := make([]byte, 0, len(encoded)/2)
+	for _, r := range encoded {
+		data = append(data, byte(r-'a'))
+	}
+	return data
+{{- else if eq .Encoding.Name "string"}}
+	return []byte(encoded)
+{{- else}}
+	return encoded
+{{- end}}
+}
+
+// content returns the content of the asset, decoded and decompressed. It is
+// immutable, so it is shared rather than copied.
+func (a *asset) content() string {
+	if a.dup != nil {
+		return a.dup.content()
+	}
+{{- if .Release}}
+	{{if .MemoryBudget}}data := {{else}}return {{end}}a.cache.get(func() string {
+		defer func() { a.data = "" }()
+		a.load()
+		return a.data
+	})
+{{- if .MemoryBudget}}
+	lru.use(a)
+	return data
+{{- end}}
+{{- else}}
+	a.once.Do(a.load)
+	return a.data
+{{- end}}
+}
+
+// load decodes and decompresses the content of the asset into data.
+func (a *asset) load() {
+{{- if .Signed}}
+	// whichever way it is read
+	defer a.verify()
+{{- end}}
+{{- if .Solid}}
+	if a.block != nil {
+		a.data = a.block.content()[a.offset : a.offset+int(a.size)]
+{{- if and .Pak (not .Signed)}}
+		if a.block.pakSpan.pak != nil {
+			a.verify()
+		}
+{{- end}}
+		return
+	}
+{{- end}}
+{{- if .Embed}}
+	if a.fsys != nil {
+		data, err := a.fsys.ReadFile(a.file)
+		if err != nil {
+			log.Panicf("Couldn't read embedded %q: %v", a.name, err)
+		}
+		a.data = string(data)
+		return
+	}
+{{- end}}
+{{- if and (eq .Encoding.Name "string") (not .Encrypt)}}
+	if !a.compressed {{if .Pak}}&& a.pakSpan.pak == nil {{end}}{
+		// the literals are the content already
+		if a.chunks == nil {
+			a.data = a.encoded
+		} else {
+			a.data = strings.Join(a.chunks, "")
+		}
+		return
+	}
+{{- end}}
+	data := a.decoded()
+{{- if .Encrypt}}
+	// the decrypted content is copied into a string, or decompressed
+	defer zero(data)
+{{- end}}
+{{- if .Codec.Import}}
+	if a.compressed {
+		var err error
+{{- if eq .Codec.Name "flate"}}
+		data, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(data)))
+{{- else if eq .Codec.Name "zstd"}}
+		data, err = zstdDecoder.DecodeAll(data, nil)
+{{- else}}
+		r, err := {{.Codec.Name}}.NewReader(bytes.NewReader(data))
+		if err != nil {
+			log.Panicf("Couldn't open {{.Codec.Name}} stream for data for %q: %v", a.name, err)
+		}
+		data, err = ioutil.ReadAll(r)
+{{- end}}
+		if err != nil {
+			log.Panicf("Couldn't decompress {{.Codec.Name}} data in %q: %v", a.name, err)
+		}
+	}
+{{- end}}
+	a.data = string(data)
+{{- if and .Pak (not .Signed)}}
+	if a.pakSpan.pak != nil {
+		a.verify()
+	}
+{{- end}}
+}
+
+{{- if .Solid}}
+// block holds small assets compressed together, as a single stream, which
+// compresses them much better than one by one. It is decoded and
+// decompressed once, the first time one of them is needed.
+type block struct {
+	encoded {{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}
+	chunks  []{{if eq .Encoding.Name "bytes"}}[]byte{{else}}string{{end}}
+{{- if .Pak}}
+	pakSpan span
+{{- end}}
+{{- if .Release}}
+
+	cache cached
+{{- else}}
+
+	once sync.Once
+	data string
+{{- end}}
+}
+
+func (b *block) content() string {
+{{- if .Release}}
+	return b.cache.get(b.load)
+{{- else}}
+	b.once.Do(func() { b.data = b.load() })
+	return b.data
+{{- end}}
+}
+
+// load decodes and decompresses the content of the block.
+func (b *block) load() string {
+	// the block is stored like a compressed asset
+	a := &asset{name: "block", compressed: true, encoded: b.encoded, chunks: b.chunks{{if .Pak}}, pakSpan: b.pakSpan{{end}}}
+	a.load()
+	return a.data
+}
+
+{{end -}}
+{{- if .Release}}
+// cached holds content loaded when first needed, until it is released, to be
+// loaded again when needed next. Loading it is safe for concurrent use.
+type cached struct {
+	mu sync.Mutex
+	// v holds a *string, nil once released
+	v atomic.Value
+}
+
+func (c *cached) get(load func() string) string {
+	if p, _ := c.v.Load().(*string); p != nil {
+		return *p
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, _ := c.v.Load().(*string); p != nil {
+		return *p
+	}
+	data := load()
+	c.v.Store(&data)
+	return data
+}
+
+func (c *cached) set(data string) {
+	c.v.Store(&data)
+}
+
+func (c *cached) release() {
+	c.v.Store((*string)(nil))
+}
+
+// release drops the content of the asset, loaded again when needed next.
+func (a *asset) release() {
+	if a.dup != nil {
+		a.dup.release()
+	}
+{{- if .MemoryBudget}}
+	lru.remove(a)
+{{- end}}
+	a.cache.release()
+}
+
+// releaseAll drops the content of the assets{{if .Solid}}, and of the blocks holding
+// them{{end}}.
+func releaseAll(files map[string]*asset) {
+	for _, a := range files {
+		a.release()
+{{- if .Solid}}
+		if a.block != nil {
+			a.block.cache.release()
+		}
+{{- end}}
+	}
+}
+{{end}}
+// bytes returns a copy of the content of the asset.
+func (a *asset) bytes() []byte {
+	return []byte(a.content())
+}
+{{if .Precompressed}}
+// gzipped returns the gzip stream of a compressed asset, kept aside to be
+// served as is.
+func (a *asset) gzipped() []byte {
+	if a.dup != nil {
+		return a.dup.gzipped()
+	}
+	a.gzOnce.Do(func() { a.gz = a.decoded() })
+	return a.gz
+}
+{{end}}
+{{- if .Brotli}}
+// brotli returns the Brotli variant of the asset, kept aside to be served as
+// is, or nil if it has none.
+func (a *asset) brotli() []byte {
+	if a.dup != nil {
+		return a.dup.brotli()
+	}
+	a.brOnce.Do(func() {
+{{- if .Pak}}
+		if a.brSpan.pak != nil {
+			a.brData = a.brSpan.read(a.name)
+		} else if len(a.br) != 0 || a.brChunks != nil {
+{{- else}}
+		if len(a.br) != 0 || a.brChunks != nil {
+{{- end}}
+			a.brData = a.join(a.br, a.brChunks)
+		}
+{{- if .Encrypt}}
+		if a.brData != nil {
+			a.brData = decrypt(a.name, a.brData)
+		}
+{{- end}}
+	})
+	return a.brData
+}
+{{end}}
+{{- if ne .Extract "none"}}
+// extract writes the content of the asset to the file dest with the mode
+// perm, creating the directories holding it. It is written to a temporary
+// file of the same directory first, renamed over dest once complete, so that
+// dest is never seen half written.{{if eq .Extract "changed"}} A file already
+// holding the content is left as it is, but for its mode.{{end}}
+func (a *asset) extract(dest string, perm fs.FileMode) error {
+{{- if eq .Extract "changed"}}
+	if a.extracted(dest) {
+		return os.Chmod(dest, perm)
+	}
+{{- end}}
+	dir := filepath.Dir(dest)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(tmp, a.content())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dest)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+{{- if eq .Extract "changed"}}
+
+// extracted tells if the file dest already holds the content of the asset,
+// from its hash.
+func (a *asset) extracted(dest string) bool {
+	f, err := os.Open(dest)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	return hex.EncodeToString(h.Sum(nil)) == a.hash
+}
+{{- end}}
+{{end}}
+{{- if .Locales}}
+// newLocaleMatcher returns a matcher of the language tags of the catalogs.
+func newLocaleMatcher(tags ...string) language.Matcher {
+	supported := make([]language.Tag, len(tags))
+	for i, tag := range tags {
+		supported[i] = language.Make(tag)
+	}
+	return language.NewMatcher(supported)
+}
+
+// matchLocale returns the index of the catalog best matching tag, a language
+// tag or a list of them weighted like in Accept-Language, and false if none
+// matches.
+func matchLocale(m language.Matcher, tag string) (int, bool) {
+	desired, _, err := language.ParseAcceptLanguage(strings.Replace(tag, "_", "-", -1))
+	if err != nil || len(desired) == 0 {
+		return 0, false
+	}
+	_, i, confidence := m.Match(desired...)
+	return i, confidence != language.No
+}
+{{end}}
+{{- if .MemoryBudget}}
+// MemoryBudget is the size in bytes of the decompressed content of the
+// assets kept in memory, above which the content of the least recently used
+// ones is released, to be decompressed again when needed. It can be changed
+// at run time, before the assets are read concurrently, and is only checked
+// when an asset is read. The blocks of small assets compressed together
+// aren't counted.
+var MemoryBudget int64 = {{.MemoryBudget}}
+
+// lru lists the assets whose content is loaded, least recently used first.
+var lru lruList
+
+type lruList struct {
+	mu    sync.Mutex
+	list  list.List
+	bytes int64
+}
+
+// use marks the asset as the most recently used, counting its size if it
+// wasn't loaded, and releases the least recently used others while over
+// budget.
+func (l *lruList) use(a *asset) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if a.used != nil {
+		l.list.MoveToBack(a.used)
+		return
+	}
+	a.used = l.list.PushBack(a)
+	l.bytes += a.size
+	for l.bytes > MemoryBudget && l.list.Front() != a.used {
+		old := l.list.Front().Value.(*asset)
+		l.drop(old)
+		old.cache.release()
+	}
+}
+
+// remove stops counting the asset, once released.
+func (l *lruList) remove(a *asset) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if a.used != nil {
+		l.drop(a)
+	}
+}
+
+func (l *lruList) drop(a *asset) {
+	l.list.Remove(a.used)
+	a.used = nil
+	l.bytes -= a.size
+}
+{{end}}
+{{- if .NFCLookups}}
+// normalized returns name in Unicode NFC, like the names of the assets.
+func normalized(name string) string {
+	return norm.NFC.String(name)
+}
+{{end}}
+{{- if .IgnoreCase}}
+// folded returns name in lower case, in which the names of the assets are
+// matched when they aren't found as they are.
+func folded(name string) string {
+	return strings.ToLower(name)
+}
+{{end}}
+{{- if .Obfuscate}}
+// obfuscated returns the key of the asset called name, which is a hash of
+// it, so that the names aren't in the binary.
+func obfuscated(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:16])
+}
+{{end}}
+{{- if .Encrypt}}
+// keyEnv is the environment variable holding the hex encoded key of the
+// assets, unless SetKey is called.
+const keyEnv = {{printf "%q" .KeyEnv}}
+
+var (
+	aeadMu sync.Mutex
+	aead   cipher.AEAD
+)
+
+// SetKey sets the AES key decrypting the assets, of 16, 24 or 32 bytes,
+// instead of reading it from ${{.KeyEnv}}. It must be called before any
+// asset is read. The key can be zeroed once it returns.
+func SetKey(key []byte) error {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(c)
+	if err != nil {
+		return err
+	}
+	aeadMu.Lock()
+	defer aeadMu.Unlock()
+	aead = gcm
+	return nil
+}
+
+// decrypt returns the decrypted content of the asset called name.
+func decrypt(name string, sealed []byte) []byte {
+	aeadMu.Lock()
+	gcm := aead
+	aeadMu.Unlock()
+	if gcm == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(os.Getenv(keyEnv)))
+		if err == nil && len(key) == 0 {
+			err = fmt.Errorf("not set")
+		}
+		if err == nil {
+			err = SetKey(key)
+			zero(key)
+		}
+		if err != nil {
+			log.Panicf("Couldn't read the key of the assets from ${{.KeyEnv}}: %v", err)
+		}
+		return decrypt(name, sealed)
+	}
+	n := gcm.NonceSize()
+	if len(sealed) < n {
+		log.Panicf("Couldn't decrypt %q: truncated content", name)
+	}
+	data, err := gcm.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		log.Panicf("Couldn't decrypt %q: %v", name, err)
+	}
+	return data
+}
+
+// zero overwrites decrypted content once it isn't needed anymore.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+{{end}}
+{{- if .Pak}}
+// PakDir is the directory holding the .pak files written along with the
+// package, read on first access. If empty, they are looked for next to the
+// executable, then in the working directory. If PakURL is set, they are
+// fetched from under it instead, like https://cdn.example.com/assets, with
+// range requests. They must be set before any asset is read.
+var (
+	PakDir string
+	PakURL string
+)
+
+// pak is a file holding the stored content of assets, of size bytes, whose
+// header records the hex encoded SHA-256 sum of the rest.
+type pak struct {
+	file string
+	size int64
+	sum  string
+
+	once sync.Once
+	r    io.ReaderAt
+	// where is the path or the URL the pak is read from
+	where string
+	err   error
+}
+
+// span is where the stored content of an asset is found in a pak.
+type span struct {
+	pak   *pak
+	at, n int64
+}
+
+// open opens the pak once, and checks it is the one the package was
+// generated with.
+func (p *pak) open() error {
+	p.once.Do(func() {
+		if PakURL != "" {
+			r := remotePak(strings.TrimSuffix(PakURL, "/") + "/" + p.file)
+			p.r, p.where = r, string(r)
+			p.err = p.check()
+			return
+		}
+		dirs := []string{PakDir}
+		if PakDir == "" {
+			dirs = []string{"."}
+			if exe, err := os.Executable(); err == nil {
+				dirs = append([]string{filepath.Dir(exe)}, dirs...)
+			}
+		}
+		var f *os.File
+		for _, dir := range dirs {
+			if f, p.err = os.Open(filepath.Join(dir, p.file)); !os.IsNotExist(p.err) {
+				break
+			}
+		}
+		if p.err == nil {
+			p.r, p.where = f, f.Name()
+			p.err = p.check()
+		}
+	})
+	return p.err
+}
+
+func (p *pak) check() error {
+	// neither cut short nor longer
+	b := make([]byte, 1)
+	if _, err := p.r.ReadAt(b, p.size-1); err == io.EOF {
+		return fmt.Errorf("%s is shorter than %d bytes", p.where, p.size)
+	} else if err != nil {
+		return err
+	}
+	if n, _ := p.r.ReadAt(b, p.size); n != 0 {
+		return fmt.Errorf("%s is longer than %d bytes", p.where, p.size)
+	}
+	sum := make([]byte, len(p.sum))
+	if _, err := p.r.ReadAt(sum, {{.PakSumAt}}); err != nil {
+		return err
+	}
+	if string(sum) != p.sum {
+		return fmt.Errorf("%s isn't the file the package was generated with", p.where)
+	}
+	return nil
+}
+
+// read returns the stored content of the asset called name.
+func (s span) read(name string) []byte {
+	if err := s.pak.open(); err != nil {
+		log.Panicf("Couldn't open the pak of %q: %v", name, err)
+	}
+	data := make([]byte, s.n)
+	if _, err := s.pak.r.ReadAt(data, s.at); err != nil {
+		log.Panicf("Couldn't read %q from %s: %v", name, s.pak.where, err)
+	}
+	return data
+}
+
+// remotePak is the URL of a pak, read with range requests.
+type remotePak string
+
+func (r remotePak) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, string(r), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	default:
+		return 0, fmt.Errorf("%s: %s", r, resp.Status)
+	}
+	return io.ReadFull(resp.Body, p)
+}
+
+{{end}}
+{{- if or .Pak .Signed}}
+// verify panics if the content of the asset isn't the one it was generated
+// with, dropping it.
+func (a *asset) verify() {
+	if sum := sha256.Sum256([]byte(a.data)); a.hash != "" && hex.EncodeToString(sum[:]) != a.hash {
+		a.data = ""
+		log.Panicf("Corrupted content for %q", a.name)
+	}
+}
+{{end}}
+{{- if .Signed}}
+// PublicKey is the hex encoded ed25519 public key the manifests of the static
+// assets are signed with.
+const PublicKey = {{printf "%q" .PublicKey}}
+
+// verifyManifest panics unless the manifest of the assets of root is signed
+// with PublicKey, and files are the assets it lists, with their size and
+// hash. The content of each asset is checked against its hash when read.
+func verifyManifest(root, manifest, signature string, files map[string]*asset) {
+	key, _ := hex.DecodeString(PublicKey)
+	sig, err := hex.DecodeString(signature)
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), []byte(manifest), sig) {
+		log.Panicf("Invalid signature of the manifest of %s", root)
+	}
+	n := 0
+	for _, line := range strings.Split(manifest, "\n") {
+		if line == "" {
+			continue
+		}
+		n++
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			log.Panicf("Invalid line in the manifest of %s: %q", root, line)
+		}
+		name, err := strconv.Unquote(fields[2])
+		if err != nil {
+			log.Panicf("Invalid line in the manifest of %s: %q", root, line)
+		}
+		a, ok := files[name]
+		if !ok || a.hash != fields[0] || strconv.FormatInt(a.size, 10) != fields[1] {
+			log.Panicf("%q doesn't match the manifest of %s", name, root)
+		}
+	}
+	if n != len(files) {
+		log.Panicf("Assets of %s missing from its manifest", root)
+	}
+}
+{{end}}
+{{- if .Split}}
+// joinAssets puts together the assets of a root split across files.
+func joinAssets(parts ...map[string]*asset) map[string]*asset {
+	n := 0
+	for _, part := range parts {
+		n += len(part)
+	}
+	assets := make(map[string]*asset, n)
+	for _, part := range parts {
+		for name, a := range part {
+			assets[name] = a
+		}
+	}
+	return assets
+}
+{{end}}
+// readDir returns the entries of the directory called name in tree, sorted by
+// filename.
+func readDir(files map[string]*asset, tree map[string][]string, name string) ([]fs.DirEntry, error) {
+	children, ok := tree[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, dirEntry(files, path.Join(name, child)))
+	}
+	return entries, nil
+}
+
+// dirEntry returns the entry of the file or directory called name.
+func dirEntry(files map[string]*asset, name string) fileInfo {
+	if a, ok := files[name]; ok {
+		return a.info()
+	}
+	return dirInfo(path.Base(name))
+}
+
+// walk walks tree from its root like fs.WalkDir.
+func walk(files map[string]*asset, tree map[string][]string, fn fs.WalkDirFunc) error {
+	err := walkDir(files, tree, ".", dirInfo("."), fn)
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walkDir(files map[string]*asset, tree map[string][]string, name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			// skip the directory only
+			err = nil
+		}
+		return err
+	}
+	for _, child := range tree[name] {
+		childname := path.Join(name, child)
+		if err := walkDir(files, tree, childname, dirEntry(files, childname), fn); err != nil {
+			if err == fs.SkipDir {
+				// skip the rest of the directory
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// names returns the sorted names of files.
+func names(files map[string]*asset) []string {
+	out := make([]string, 0, len(files))
+	for name := range files {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// glob returns the sorted names of the files matching pattern.
+func glob(files map[string]*asset, pattern string) []string {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil
+	}
+	var matches []string
+	for name := range files {
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// fileInfo is both the fs.FileInfo and the fs.DirEntry of an asset or of a
+// directory derived from the asset names.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// dirInfo returns the information of the directory called name.
+func dirInfo(name string) fileInfo {
+	return fileInfo{name: name, mode: fs.ModeDir | 0555}
+}
+
+func (fi fileInfo) Name() string               { return fi.name }
+func (fi fileInfo) Size() int64                { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode          { return fi.mode }
+func (fi fileInfo) ModTime() time.Time         { return fi.modTime }
+func (fi fileInfo) IsDir() bool                { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() interface{}           { return nil }
+func (fi fileInfo) Type() fs.FileMode          { return fi.mode.Type() }
+func (fi fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+`))
+
+var httptempl = template.Must(template.New("http").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+
+package {{.PkgName}}
+
+import ({{if or .Precompressed .Brotli}}
+	"bytes"{{end}}
+	"io"{{if .Overrides}}
+	"mime"{{end}}
+	"net/http"
+	"os"
+	"path"{{if .Overrides}}
+	"path/filepath"{{end}}{{if or .Precompressed .Brotli}}
+	"strconv"{{end}}
+	"strings"
+)
+
+// CacheControl is the value of the Cache-Control header sent by the handlers,
+// none is sent if empty. Assets are served with an ETag, so clients can
+// revalidate them cheaply.
+var CacheControl = {{printf "%q" .CacheControl}}
+
+// FileSystem implements http.FileSystem over a set of static assets. Files
+// are served from memory and directories are derived from the asset names.
+type FileSystem struct {
+	fsys FS
+}
+
+// compile check
+var _ http.FileSystem = FileSystem{}
+
+// Open returns the file or directory found at name.
+func (fs FileSystem) Open(name string) (http.File, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	if a, ok := fs.fsys.lookup(name); ok {
+		return &file{
+			Reader: strings.NewReader(a.content()),
+			info:   a.info(),
+		}, nil
+	}
+
+	dir := name
+	if dir == "" {
+		dir = "."
+	}
+	children, ok := fs.fsys.tree()[dir]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: "/" + name, Err: os.ErrNotExist}
+	}
+	files := fs.fsys.files()
+	entries := make([]os.FileInfo, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, dirEntry(files, path.Join(dir, child)))
+	}
+
+	return &file{
+		Reader:  strings.NewReader(""),
+		info:    dirInfo(path.Base("/" + name)),
+		entries: entries,
+	}, nil
+}
+
+type file struct {
+	*strings.Reader
+	info    fileInfo
+	entries []os.FileInfo
+}
+
+func (f *file) Close() error { return nil }
+
+func (f *file) Stat() (os.FileInfo, error) { return f.info, nil }
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: f.info.name, Err: os.ErrInvalid}
+	}
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.entries) {
+		count = len(f.entries)
+	}
+	entries := f.entries[:count]
+	f.entries = f.entries[count:]
+	return entries, nil
+}
+
+// handler serves a set of static assets over HTTP. A directory is served by
+// its index.html file, once redirected to its path with a trailing slash.
+type handler struct {
+	fsys   FS
+	prefix string
+	// fallback names the asset served in place of those not found, if set
+	fallback string
+{{- if .Overrides}}
+	// overrides is the directory holding the files served in place of the
+	// assets of the same names, if set
+	overrides string
+{{- end}}
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, h.prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path[len(h.prefix):]), "/")
+
+	a, ok := h.fsys.lookup(name)
+	if !ok {
+		if a, ok = h.fsys.lookup(path.Join(name, "index.html")); ok && name != "" && !strings.HasSuffix(r.URL.Path, "/") {
+			// relative links of the index need the trailing slash
+			target := path.Base(r.URL.Path) + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			w.Header().Set("Location", target)
+			w.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+	}
+	if !ok && h.fallback != "" {
+		a, ok = h.fsys.lookup(h.fallback)
+	}
+{{- if .Overrides}}
+	if h.overrides != "" && (h.serveOverride(w, r, name) || ok && a.name != name && h.serveOverride(w, r, a.name)) {
+		return
+	}
+{{- end}}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if CacheControl != "" {
+		w.Header().Set("Cache-Control", CacheControl)
+	}
+	if a.contentType != "" {
+		w.Header().Set("Content-Type", a.contentType)
+	}
+{{- if .Brotli}}
+	// the Brotli variant is smaller than the gzip content, when there is one
+	if br := a.brotli(); br != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if accepts(r, "br") {
+			w.Header().Set("Content-Encoding", "br")
+			w.Header().Set("ETag", "\""+a.hash+"-br\"")
+			http.ServeContent(w, r, a.name, a.info().modTime, bytes.NewReader(br))
+			return
+		}
+	}
+{{- end}}
+{{- if .Precompressed}}
+	if a.compressed {
+		if w.Header().Get("Vary") == "" {
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+		if accepts(r, "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("ETag", "\""+a.hash+"-gzip\"")
+			http.ServeContent(w, r, a.name, a.info().modTime, bytes.NewReader(a.gzipped()))
+			return
+		}
+	}
+{{- end}}
+
+	w.Header().Set("ETag", "\""+a.hash+"\"")
+	// ServeContent answers conditional requests with the ETag
+	http.ServeContent(w, r, a.name, a.info().modTime, strings.NewReader(a.content()))
+}
+{{- if .Overrides}}
+
+// serveOverride serves the file called name from the overrides directory,
+// telling if there is one. It is read on each request, and sent with
+// Cache-Control: no-cache, so that changes show right away.
+func (h handler) serveOverride(w http.ResponseWriter, r *http.Request, name string) bool {
+	f, err := os.Open(filepath.Join(h.overrides, filepath.FromSlash(name)))
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	http.ServeContent(w, r, name, fi.ModTime(), f)
+	return true
+}
+{{- end}}
+{{- if or .Precompressed .Brotli}}
+
+// accepts tells if the client accepts the content coding, like gzip, looking
+// at the Accept-Encoding header of the request.
+func accepts(r *http.Request, coding string) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, q := strings.TrimSpace(enc), 1.0
+		if i := strings.Index(name, ";"); i >= 0 {
+			param := strings.TrimSpace(name[i+1:])
+			name = strings.TrimSpace(name[:i])
+			if strings.HasPrefix(param, "q=") {
+				var err error
+				if q, err = strconv.ParseFloat(param[2:], 64); err != nil {
+					q = 0
+				}
+			}
+		}
+		if name == coding {
+			return q > 0
+		}
+	}
+	return false
+}
+{{- end}}
+`))
+
+var fstempl = template.Must(template.New("fs").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+
+package {{.PkgName}}
+
+import (
+	"io/fs"{{if .HTTP}}
+	"net/http"{{end}}
+	"sort"
+)
+
+// FS holds a set of static assets. It is a value, returned by the FS function
+// of each directory, to pass the static assets around.{{if .IOFS}} It implements
+// fs.FS, fs.ReadDirFS, fs.ReadFileFS, fs.StatFS and fs.GlobFS.{{end}}
+type FS struct {
+	// lookup finds the file called name, files lists them all, and tree
+	// lists the files and directories of each directory
+	lookup func(name string) (*asset, bool)
+	files  func() map[string]*asset
+	tree   func() map[string][]string
+}
+
+// Overlay returns an FS holding the files of primary laid over those of the
+// fallbacks: a name is looked up in primary first, then in each fallback in
+// turn, and a directory holds the files of all of them. The layers are read
+// on each lookup, so that the files of a DirStore stay up to date, and only
+// put together to list the files.
+func Overlay(primary FS, fallbacks ...FS) FS {
+	layers := append([]FS{primary}, fallbacks...)
+	lookup := func(name string) (*asset, bool) {
+		for _, l := range layers {
+			if a, ok := l.lookup(name); ok {
+				return a, true
+			}
+		}
+		return nil, false
+	}
+	files := func() map[string]*asset {
+		files := make(map[string]*asset)
+		// the first layers are copied last, to win
+		for i := len(layers) - 1; i >= 0; i-- {
+			for name, a := range layers[i].files() {
+				files[name] = a
+			}
+		}
+		return files
+	}
+	tree := func() map[string][]string {
+		children := make(map[string]map[string]bool)
+		for _, l := range layers {
+			for dir, names := range l.tree() {
+				if children[dir] == nil {
+					children[dir] = make(map[string]bool)
+				}
+				for _, name := range names {
+					children[dir][name] = true
+				}
+			}
+		}
+		tree := make(map[string][]string, len(children))
+		for dir, names := range children {
+			for name := range names {
+				tree[dir] = append(tree[dir], name)
+			}
+			sort.Strings(tree[dir])
+		}
+		return tree
+	}
+	return FS{lookup: lookup, files: files, tree: tree}
+}
+
+// ReadFile returns a copy of the content of the file found at name.
+func (fsys FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	a, ok := fsys.lookup(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return a.bytes(), nil
+}
+
+// Names returns the sorted names of all the files, without reading them.
+func (fsys FS) Names() []string {
+	return names(fsys.files())
+}
+
+// Hash returns the hex encoded SHA-256 of the content of the file found at
+// name, and true if found, false otherwise.
+func (fsys FS) Hash(name string) (string, bool) {
+	a, ok := fsys.lookup(name)
+	if !ok {
+		return "", false
+	}
+	return a.hash, true
+}
+{{- if .HTTP}}
+
+// HTTP returns an http.FileSystem serving the files, suitable for use with
+// http.FileServer.
+func (fsys FS) HTTP() http.FileSystem {
+	return FileSystem{fsys}
+}
+
+// Handler returns an http.Handler serving the files at the path of each
+// request, once trimmed of prefix, like the Handler function of each
+// directory.
+func (fsys FS) Handler(prefix string) http.Handler {
+	return handler{fsys: fsys, prefix: prefix}
+}
+{{- end}}
+`))
+
+var iofstempl = template.Must(template.New("iofs").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+
+package {{.PkgName}}
+
+import (
+	"io"
+	"io/fs"{{if .HTTP}}
+	"net/http"{{end}}
+	"path"
+	"sort"
+	"strings"
+)
+
+// compile check
+var (
+	_ fs.FS         = FS{}
+	_ fs.ReadDirFS  = FS{}
+	_ fs.ReadFileFS = FS{}
+	_ fs.StatFS     = FS{}
+	_ fs.GlobFS     = FS{}
+	_ AssetStore    = FS{}
+)
+
+// AssetStore holds static assets. It is implemented by the FS of each
+// directory, and by the one returned by DirStore, so that tests can use
+// fixture directories in place of the static assets, or mock them.
+type AssetStore interface {
+	fs.ReadDirFS
+	fs.ReadFileFS
+	fs.StatFS
+	fs.GlobFS
+	Names() []string
+	Hash(name string) (string, bool)
+{{- if .HTTP}}
+	HTTP() http.FileSystem
+	Handler(prefix string) http.Handler
+{{- end}}
+}
+
+// DirStore returns an FS holding the files found in the directory called
+// dir, named after their path relative to it. They are read from disk each
+// time they are looked up, so changes show up right away, a single file
+// being read on its own and the whole directory only to list it. Symlinks are
+// followed.
+func DirStore(dir string) FS {
+	r := devRoot{prefix: ".", dir: dir, symlinks: true}
+	tree := func() map[string][]string {
+		return devTree(r.files())
+	}
+	return FS{lookup: r.lookup, files: r.files, tree: tree}
+}
+
+// Open returns the file or directory found at name.
+func (fsys FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if a, ok := fsys.lookup(name); ok {
+		return &fsFile{
+			Reader: strings.NewReader(a.content()),
+			info:   a.info(),
+		}, nil
+	}
+	entries, err := readDir(fsys.files(), fsys.tree(), name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &fsDir{
+		info:    dirInfo(path.Base(name)),
+		entries: entries,
+	}, nil
+}
+
+// ReadDir returns the entries of the directory found at name, sorted by
+// filename.
+func (fsys FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return readDir(fsys.files(), fsys.tree(), name)
+}
+
+// Stat returns the information of the file or directory found at name.
+func (fsys FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if a, ok := fsys.lookup(name); ok {
+		return a.info(), nil
+	}
+	if _, ok := fsys.tree()[name]; !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return dirInfo(path.Base(name)), nil
+}
+
+// Glob returns the names of all files and directories matching pattern,
+// with the syntax of path.Match.
+func (fsys FS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var matches []string
+	for dir, children := range fsys.tree() {
+		for _, child := range children {
+			name := path.Join(dir, child)
+			if ok, _ := path.Match(pattern, name); ok {
+				matches = append(matches, name)
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+type fsFile struct {
+	*strings.Reader
+	info fileInfo
+}
+
+func (f *fsFile) Close() error { return nil }
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+type fsDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+}
+
+func (d *fsDir) Close() error { return nil }
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *fsDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+`))
+
+var fstesttempl = template.Must(template.New("fstest").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+
+package {{.PkgName}}
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS{{.RootName}}(t *testing.T) {
+	err := fstest.TestFS(FS{{.RootName}}(),{{range .Entries}}
+		{{printf "%q" .Name}},{{end}}
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+`))
+
+var gentesttempl = template.Must(template.New("gentest").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+{{if .Dev}}
+//go:build !dev
+{{end}}
+package {{.PkgName}}
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// TestAssets makes sure that every static asset can be read back, with the
+// size and the hash it had when it was embedded.
+func TestAssets(t *testing.T) {
+	roots := map[string]map[string]*asset{ {{- range .Roots}}
+		{{printf "%q" .}}: files{{.}}(),{{end}}
+	}
+	for root, files := range roots {
+		files := files
+		t.Run(root, func(t *testing.T) {
+			for name, a := range files {
+				data, err := readAsset(a)
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+					continue
+				}
+				if int64(len(data)) != a.size {
+					t.Errorf("%s: got %d bytes, want %d", name, len(data), a.size)
+				}
+				sum := sha256.Sum256([]byte(data))
+				if hash := hex.EncodeToString(sum[:]); hash != a.hash {
+					t.Errorf("%s: got hash %s, want %s", name, hash, a.hash)
+				}
+			}
+		})
+	}
+}
+
+// TestConcurrentAssets reads the static assets from several goroutines at
+// once, each reading the first asset of a root along with the others in
+// turn, for go test -race to check that they are decompressed{{if .Release}}, and released,{{end}}
+// safely.
+func TestConcurrentAssets(t *testing.T) {
+	roots := map[string]map[string]*asset{ {{- range .Roots}}
+		{{printf "%q" .}}: files{{.}}(),{{end}}
+	}
+	for root, files := range roots {
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := range names {
+					for _, name := range []string{names[0], names[(i+j)%len(names)]} {
+						a := files[name]
+						data, err := readAsset(a)
+						if err != nil {
+							t.Errorf("%s: %s: %v", root, name, err)
+							return
+						}
+						if sum := sha256.Sum256([]byte(data)); hex.EncodeToString(sum[:]) != a.hash {
+							t.Errorf("%s: %s: got the wrong content", root, name)
+						}
+{{- if .Release}}
+						if (i+j)%2 == 0 {
+							a.release()
+						}
+{{- end}}
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+}
+
+// readAsset returns the content of a, or the panic met decoding it.
+func readAsset(a *asset) (data string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return a.content(), nil
+}
+`))
+
+var doctempl = template.Must(template.New("doc").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+
+// Package {{.PkgName}} holds static assets, generated by
+// https://github.com/aybabtme/gostatic{{if .Command}} with:
+//
+//	{{.Command}}
+//
+// Run it again to regenerate the package.{{else}}.{{end}} It holds:
+//{{range .Roots}}
+//	{{.}}{{end}}
+package {{.PkgName}}
+{{- if .Generate}}
+
+//go:generate {{.Generate}}
+{{- end}}
+`))
+
+var compattempl = template.Must(template.New("compat").Parse(`// Code written by gostatic migrate, to keep the API of the package generated
+// by {{.Tool}} on top of the functions of gostatic. Unlike the other files, it
+// is kept by later generations: remove it once no code uses that API.
+
+//go:generate {{.Generate}}
+
+package {{.PkgName}}
+{{- if eq .Tool "go-bindata"}}
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Asset loads and returns the asset for the given name. It returns an error
+// if the asset could not be found or could not be loaded.
+func Asset(name string) ([]byte, error) {
+	r, ok := Get{{.RootName}}(strings.Replace(name, "\\", "/", -1))
+	if !ok {
+		return nil, fmt.Errorf("Asset %s not found", name)
+	}
+	return io.ReadAll(r)
+}
+
+// AssetString returns the asset contents as a string (instead of a []byte).
+func AssetString(name string) (string, error) {
+	data, err := Asset(name)
+	return string(data), err
+}
+
+// MustAsset is like Asset but panics when Asset would return an error. It
+// simplifies safe initialization of global variables.
+func MustAsset(name string) []byte {
+	data, err := Asset(name)
+	if err != nil {
+		panic("asset: Asset(" + name + "): " + err.Error())
+	}
+	return data
+}
+
+// MustAssetString is like AssetString but panics when Asset would return an
+// error. It simplifies safe initialization of global variables.
+func MustAssetString(name string) string {
+	return string(MustAsset(name))
+}
+
+// AssetInfo loads and returns the asset info for the given name. It returns
+// an error if the asset could not be found or could not be loaded.
+func AssetInfo(name string) (os.FileInfo, error) {
+	info, ok := Stat{{.RootName}}(strings.Replace(name, "\\", "/", -1))
+	if !ok {
+		return nil, fmt.Errorf("AssetInfo %s not found", name)
+	}
+	return info, nil
+}
+
+// AssetNames returns the names of the assets.
+func AssetNames() []string {
+	return Names{{.RootName}}()
+}
+
+// AssetDir returns the file names below a certain directory embedded in the
+// file by go-bindata. The root directory is the empty string.
+func AssetDir(name string) ([]string, error) {
+	dir := "."
+	if name != "" {
+		dir = path.Clean(strings.Replace(name, "\\", "/", -1))
+	}
+	entries, err := ReadDir{{.RootName}}(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error not found")
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
+
+// RestoreAsset restores an asset under the given directory.
+func RestoreAsset(dir, name string) error {
+	data, err := Asset(name)
+	if err != nil {
+		return err
+	}
+	info, err := AssetInfo(name)
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, data, info.Mode()); err != nil {
+		return err
+	}
+	return os.Chtimes(filename, info.ModTime(), info.ModTime())
+}
+
+// RestoreAssets restores an asset under the given directory recursively.
+func RestoreAssets(dir, name string) error {
+	children, err := AssetDir(name)
+	// File
+	if err != nil {
+		return RestoreAsset(dir, name)
+	}
+	// Dir
+	for _, child := range children {
+		if err := RestoreAssets(dir, path.Join(name, child)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+{{- else if eq .Tool "statik"}}
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+
+	statikfs "github.com/rakyll/statik/fs"
+)
+
+// init registers the files with statik, as the package it replaces did, so
+// that statikfs.New keeps serving them.
+func init() {
+	// statik reads the files from a zip archive
+	buf := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(buf)
+	err := Walk{{.RootName}}(func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		header.Method = zip.Store
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		r, _ := Get{{.RootName}}(name)
+		_, err = io.Copy(w, r)
+		return err
+	})
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		panic("statik: couldn't archive the files: " + err.Error())
+	}
+	{{if .Namespace}}statikfs.RegisterWithNamespace({{printf "%q" .Namespace}}, buf.String()){{else}}statikfs.Register(buf.String()){{end}}
+}
+{{- else if eq .Tool "packr"}}
+
+import (
+	"io"
+	"strings"
+
+	"github.com/gobuffalo/packr"
+)
+
+// init packs the files in the packr boxes they were found in, as the file it
+// replaces did, so that the boxes keep serving them once the package is
+// imported.
+func init() {
+	boxes := []struct {
+		name   string
+		prefix string
+	}{ {{- range .Boxes}}
+		{ {{- printf "%q" .Name}}, {{printf "%q" .Prefix}}},{{end}}
+	}
+	for name, r := range List{{.RootName}}() {
+		data, _ := io.ReadAll(r)
+		for _, box := range boxes {
+			if box.prefix == "" {
+				packr.PackBytes(box.name, name, data)
+			} else if strings.HasPrefix(name, box.prefix+"/") {
+				packr.PackBytes(box.name, strings.TrimPrefix(name, box.prefix+"/"), data)
+			}
+		}
+	}
+}
+{{- end}}
+`))
+
+var benchtempl = template.Must(template.New("bench").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+{{if .Dev}}
+//go:build !dev
+{{end}}
+package {{.PkgName}}
+
+import (
+	"bytes"{{if .HTTP}}
+	"net/http"
+	"net/http/httptest"{{end}}
+	"sort"
+	"testing"
+)
+
+// benchRoots are the roots of the static assets the benchmarks run on.
+var benchRoots = []struct {
+	name  string
+	files func() map[string]*asset
+	get   func(string) (*bytes.Reader, bool){{if .HTTP}}
+	handler http.Handler{{end}}
+}{ {{- range .Roots}}
+	{ {{- printf "%q" .}}, files{{.}}, Get{{.}}{{if $.HTTP}}, Handler{{.}}("/"){{end}}},{{end}}
+}
+
+// benchNames returns the sorted names of the static assets of files.
+func benchNames(files map[string]*asset) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BenchmarkLoad measures decoding and decompressing all the static assets of
+// each root, which is done at init, or when each is first needed with -lazy.
+// The blocks of small assets compressed together are only decompressed once.
+func BenchmarkLoad(b *testing.B) {
+	for _, r := range benchRoots {
+		files := r.files()
+		b.Run(r.name, func(b *testing.B) {
+			var size int64
+			for _, a := range files {
+				size += a.size
+			}
+			b.SetBytes(size)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, a := range files {
+					// the content of duplicates is loaded by the asset they
+					// point to
+					if a.dup == nil {
+						a.load()
+{{- if .Release}}
+						a.data = ""
+{{- end}}
+					}
+				}
+			}
+		})
+	}
+}
+{{- if not .Obfuscate}}
+
+// BenchmarkGet measures Get on the static assets of each root in turn, once
+// they are loaded, which copies their content.
+func BenchmarkGet(b *testing.B) {
+	for _, r := range benchRoots {
+		names := benchNames(r.files())
+		if len(names) == 0 {
+			continue
+		}
+		for _, name := range names {
+			r.get(name)
+		}
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, ok := r.get(names[i%len(names)]); !ok {
+					b.Fatalf("%q not found", names[i%len(names)])
+				}
+			}
+		})
+	}
+}
+{{- end}}
+{{- if .HTTP}}
+
+// BenchmarkHandler measures the handler serving the static assets of each
+// root in turn, once they are loaded.
+func BenchmarkHandler(b *testing.B) {
+	for _, r := range benchRoots {
+		names := benchNames(r.files())
+		if len(names) == 0 {
+			continue
+		}
+		reqs := make([]*http.Request, len(names))
+		for i, name := range names {
+			reqs[i] = httptest.NewRequest(http.MethodGet, "/", nil)
+			reqs[i].URL.Path = "/" + name
+			r.handler.ServeHTTP(httptest.NewRecorder(), reqs[i])
+		}
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w := httptest.NewRecorder()
+				r.handler.ServeHTTP(w, reqs[i%len(reqs)])
+				if w.Code != http.StatusOK {
+					b.Fatalf("%s: got status %d", reqs[i%len(reqs)].URL.Path, w.Code)
+				}
+			}
+		})
+	}
+}
+{{- end}}
+`))
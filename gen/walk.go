@@ -0,0 +1,76 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// walk is like filepath.Walk, except that the root and, if follow is set,
+// the symlinks found in it are followed. fn is given the information of the
+// files they point to, and a symlink to a directory holding it is an error,
+// since following it would never end. Otherwise symlinks are given to fn
+// like any other file.
+func walk(root string, follow bool, fn filepath.WalkFunc) error {
+	fi, err := os.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	if err := walkDir(root, fi, follow, nil, fn); err != filepath.SkipDir {
+		return err
+	}
+	return nil
+}
+
+// walkDir walks the file called name, given the directories holding it.
+func walkDir(name string, fi os.FileInfo, follow bool, parents []os.FileInfo, fn filepath.WalkFunc) error {
+	if err := fn(name, fi, nil); err != nil || !fi.IsDir() {
+		return err
+	}
+	for _, parent := range parents {
+		if os.SameFile(parent, fi) {
+			return fmt.Errorf("%s leads back to a directory holding it", name)
+		}
+	}
+	names, err := readDirNames(name)
+	if err != nil {
+		return fn(name, fi, err)
+	}
+	parents = append(parents, fi)
+	for _, base := range names {
+		filename := filepath.Join(name, base)
+		fi, err := os.Lstat(filename)
+		if err == nil && follow && fi.Mode()&os.ModeSymlink != 0 {
+			fi, err = os.Stat(filename)
+		}
+		if err != nil {
+			if err := fn(filename, fi, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if err := walkDir(filename, fi, follow, parents, fn); err == filepath.SkipDir && !fi.IsDir() {
+			// the rest of the directory is skipped
+			return nil
+		} else if err != nil && err != filepath.SkipDir {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDirNames returns the sorted names of the files of a directory.
+func readDirNames(dirname string) ([]string, error) {
+	dir, err := os.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	names, err := dir.Readdirnames(-1)
+	_ = dir.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
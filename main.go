@@ -8,248 +8,856 @@ exposing the filepaths in the list of directories you provided. The
 data is compressed and decompressed at init time, which means that
 the bundled data is typically _smaller_ than the original one
 living on your filesystem.
+
+The generation itself is done by package gen, which other tools can
+use directly.
 */
 package main
 
 import (
-	"bytes"
-	"compress/gzip"
-	"encoding/base64"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
 	"flag"
+	"fmt"
 	"github.com/aybabtme/color/brush"
+	"github.com/aybabtme/gostatic/gen"
 	"github.com/dustin/go-humanize"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"text/tabwriter"
-	"text/template"
-	"unicode"
 )
 
 var (
-	pkgname = "staticfs"
-	elog    = log.New(newLogtab(os.Stderr), brush.Red("[error] ").String(), 0)
+	elog = log.New(newLogtab(os.Stderr), "[error] ", 0)
+
+	// the logging flags, common to every command
+	quiet, verbose, noColor bool
 )
 
-func main() {
+// commands are the subcommands of gostatic, gen being the default one.
+var commands = map[string]func(ctx context.Context, args []string){
+	"gen":     runGen,
+	"list":    runList,
+	"verify":  runVerify,
+	"extract": runExtract,
+	"diff":    runDiff,
+	"migrate": runMigrate,
+}
 
-	flag.StringVar(&pkgname, "pkgname", "staticfs", "name of the package to create")
-	flag.Parse()
+func main() {
 
-	log.SetOutput(newLogtab(os.Stdout))
-	log.SetPrefix(brush.Blue("[info] ").String())
 	log.SetFlags(0)
+	setupLogs()
 
-	if len(flag.Args()) < 1 {
-		elog.Fatalf(`Need to specify at least one directory.
-usage: %s [dirnames]`, os.Args[0])
-		return
+	name, args := "gen", os.Args[1:]
+	if len(args) != 0 {
+		if _, ok := commands[args[0]]; ok {
+			name, args = args[0], args[1:]
+		}
 	}
+	// an interrupted command stops cleanly, leaving its output as it was
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	commands[name](ctx, args)
+}
 
-	if err := os.Mkdir(pkgname, 0744); err != nil {
-		elog.Fatalf("Couldn't create package directory: %v", err)
+// runGen generates a package, and maybe keeps it up to date.
+func runGen(ctx context.Context, args []string) {
+	fs := newFlagSet("gen", "[flags] dirnames or filenames", "Generate a package holding the content of the directories and files.")
+	options := genFlags(fs)
+	check := fs.Bool("check", false, "don't write anything, exit with an error if the package is out of date")
+	watching := fs.Bool("watch", false, "keep running and regenerate the package when files change")
+	dryRun := fs.Bool("dry-run", false, "don't write anything, print the sizes of the files that would be embedded and the files left out")
+	report := fs.String("report", "", "write a report of the generation in this format, only json is supported")
+	reportOut := fs.String("report-out", "-", "file to write the report to, - for the standard output")
+	stats := fs.Int("stats", 0, "print a summary of the sizes at the end, with this many of the extensions and files storing the most")
+	timeout := fs.Duration("timeout", 0, "give up generating the package after this long, like 2m, 0 for no limit")
+	parseFlags(fs, args)
+	pkgs := options()
+
+	genCtx := ctx
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		genCtx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
 	}
-	log.Printf("Created directory for package %q", pkgname)
-	for _, arg := range flag.Args() {
 
-		err := writeDirectory(arg)
+	var reportTo io.Writer
+	switch *report {
+	case "":
+	case "json":
+		if *reportOut == "-" {
+			// keep the standard output for the report
+			reportTo = os.Stdout
+			break
+		}
+		file, err := os.Create(*reportOut)
 		if err != nil {
-			elog.Printf("Failed to snapshot %q, %v", arg, err)
+			elog.Fatalf("Couldn't create report: %v", err)
+		}
+		defer func() { _ = file.Close() }()
+		reportTo = file
+	default:
+		elog.Fatalf("Invalid -report %q, want json", *report)
+	}
+	for i := range pkgs {
+		opts := &pkgs[i]
+		opts.Stats = *stats
+		// each package writes its own report
+		opts.Report = reportTo
+		if reportTo == os.Stdout {
+			if opts.Log != nil {
+				opts.Log.SetOutput(newLogtab(os.Stderr))
+			}
+			if opts.Progress != nil {
+				opts.Progress = os.Stderr
+			}
 		}
+	}
 
+	if *dryRun {
+		for i, opts := range pkgs {
+			// the summary lists the files instead
+			opts.Log = nil
+			r, err := gen.DryRun(genCtx, opts)
+			if err != nil {
+				elog.Fatal(err)
+			}
+			if opts.Report == os.Stdout {
+				continue
+			}
+			if len(pkgs) > 1 {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("Package %q:\n\n", packageLabel(opts))
+			}
+			printReport(os.Stdout, r)
+			if *stats > 0 {
+				fmt.Println()
+				for _, line := range r.Summary(*stats) {
+					fmt.Println(line)
+				}
+			}
+		}
+		return
 	}
-}
 
-func writeDirectory(dirname string) error {
+	if *check {
+		outdated := false
+		for _, opts := range pkgs {
+			diffs, err := gen.Check(genCtx, opts)
+			if err != nil {
+				elog.Fatalf("Couldn't check package %q: %v", packageLabel(opts), err)
+			}
+			for _, diff := range diffs {
+				elog.Print(diff)
+			}
+			if len(diffs) != 0 {
+				elog.Printf("Package %q is out of date, run gostatic again", packageLabel(opts))
+				outdated = true
+				continue
+			}
+			log.Printf("Package %q is up to date", packageLabel(opts))
+		}
+		if outdated {
+			os.Exit(1)
+		}
+		return
+	}
 
-	compressSize := 0
-	totalSize := 0
-	fakefs := make(map[string]string)
+	if err := gen.GenerateAll(genCtx, pkgs); err != nil {
+		elog.Fatal(err)
+	}
 
-	err := filepath.Walk(dirname, func(name string, fi os.FileInfo, err error) error {
-		if fi.IsDir() {
-			return err
+	if *watching {
+		// every package is watched on its own
+		errs := make(chan error, len(pkgs))
+		for _, opts := range pkgs {
+			go func(opts gen.Options) { errs <- watch(ctx, opts) }(opts)
 		}
-
-		data, err := ioutil.ReadFile(name)
-		if err != nil {
-			elog.Printf("couldn't read %q: %v", name, err)
-			return err
+		for range pkgs {
+			if err := <-errs; err != nil {
+				elog.Fatalf("Couldn't watch directories: %v", err)
+			}
 		}
+	}
+}
 
-		totalSize += len(data)
-		buf := bytes.NewBuffer(nil)
-		gw := gzip.NewWriter(buf)
+// packageLabel names the package of opts in messages, by its directory if
+// its name is found with -append.
+func packageLabel(opts gen.Options) string {
+	if opts.PkgName == "" {
+		return opts.Output
+	}
+	return opts.PkgName
+}
 
-		if _, err = gw.Write(data); err != nil {
-			elog.Printf("couldn't compress %q: %v", name, err)
+// printReport prints the sizes of the files of a report, the files left out,
+// and the totals.
+func printReport(w io.Writer, r *gen.Report) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROOT\tNAME\tSIZE\tSTORED\tENCODED")
+	for _, f := range r.Files {
+		stored, encoded := humanize.Bytes(uint64(f.CompressedSize)), humanize.Bytes(uint64(f.EncodedSize))
+		if f.Duplicate {
+			stored, encoded = "-", "(duplicate)"
 		}
-		if err := gw.Close(); err != nil {
-			elog.Printf("couldn't close compressed %q: %v", name, err)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Root, f.Name, humanize.Bytes(uint64(f.Size)), stored, encoded)
+	}
+	_ = tw.Flush()
+	if len(r.Filtered) != 0 || len(r.Skipped) != 0 {
+		fmt.Fprintf(w, "\nLeft out:\n")
+		for _, f := range r.Filtered {
+			fmt.Fprintf(tw, "  %s\t%s\n", f.Reason, f.Name)
+		}
+		for _, name := range r.Skipped {
+			fmt.Fprintf(tw, "  unreadable\t%s\n", name)
 		}
-		compressSize += buf.Len()
+		_ = tw.Flush()
+	}
+	fmt.Fprintf(w, "\nTotal: %d files, %s, %s stored, %s encoded\n", len(r.Files),
+		humanize.Bytes(uint64(r.Size)),
+		humanize.Bytes(uint64(r.CompressedSize)),
+		humanize.Bytes(uint64(r.EncodedSize)))
+}
 
-		gzip64data := base64.StdEncoding.EncodeToString(buf.Bytes())
+// newFlagSet returns the flags of a subcommand, with its usage and the
+// logging flags.
+func newFlagSet(name, args, doc string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s %s\n\n%s\n\n", os.Args[0], name, args, doc)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&quiet, "q", false, "only log errors")
+	fs.BoolVar(&verbose, "v", false, "log more details, like the files left out and why")
+	fs.BoolVar(&noColor, "no-color", false, "don't color the logs, the default when they don't go to a terminal or NO_COLOR is set")
+	return fs
+}
 
-		fakefs[name] = gzip64data
+// parseFlags parses the flags of a subcommand, and sets up the logs.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	_ = fs.Parse(args)
+	setupLogs()
+}
 
-		log.Printf("%s\t->\t%s\t%q",
-			humanize.Bytes(uint64(len(data))),
-			humanize.Bytes(uint64(len(gzip64data))),
-			name)
+// setupLogs sets the prefixes and outputs of the standard log, for info,
+// and of elog, for errors, following the logging flags.
+func setupLogs() {
+	if quiet && verbose {
+		elog.Fatalf("Invalid -q along with -v")
+	}
+	log.SetPrefix(logPrefix("[info] ", os.Stdout))
+	elog.SetPrefix(logPrefix("[error] ", os.Stderr))
+	if quiet {
+		log.SetOutput(ioutil.Discard)
+	} else {
+		log.SetOutput(newLogtab(os.Stdout))
+	}
+}
 
+// infoLog returns the logger of the files generated, nil with -q.
+func infoLog() *log.Logger {
+	if quiet {
 		return nil
-	})
-	if err != nil {
-		return err
 	}
+	return log.New(newLogtab(os.Stdout), logPrefix("[info] ", os.Stdout), 0)
+}
+
+// logPrefix colors the prefix of the logs written to f, blue for info and
+// red for errors, if f is a terminal and colors aren't turned off.
+func logPrefix(prefix string, f *os.File) string {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return prefix
+	}
+	if !isTerminal(f) {
+		return prefix
+	}
+	if prefix == "[error] " {
+		return brush.Red(prefix).String()
+	}
+	return brush.Blue(prefix).String()
+}
 
-	destfilename := filepath.Join(pkgname, snakify(dirname)+".go")
-	destfunction := camelize(dirname)
+// isTerminal tells if f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
 
-	log.Printf("saving to %q, usable with function Get%s and List%s", destfilename, destfunction, destfunction)
+// progressFiles is the number of files of a directory from which -progress
+// auto shows a progress bar.
+const progressFiles = 100
+
+// genFlags declares the flags configuring the generation on fs. The function
+// returned reads them, along with the directories, once fs is parsed, and
+// returns the options of each package, a single one unless -spec is given.
+func genFlags(fs *flag.FlagSet) func() []gen.Options {
+	var opts gen.Options
+	fs.StringVar(&opts.PkgName, "pkgname", "staticfs", "name of the package to create, or its path, like internal/assets/staticfs, named after its last element")
+	fs.BoolVar(&opts.SanitizePkgName, "sanitize-pkgname", false, "turn a -pkgname that isn't a valid package name into one, with a warning, instead of failing")
+	fs.StringVar(&opts.Output, "o", "", "directory to write the package to, created if needed, the package name by default")
+	fs.StringVar(&opts.Out, "out", "", "write a single file to drop in an existing package named -pkgname, instead of a directory")
+	fs.BoolVar(&opts.KeepStale, "keep-stale", false, "keep the files generated before that aren't generated anymore")
+	fs.BoolVar(&opts.Append, "append", false, "write the files in the existing package found at -o, under its name, suffixing them with _gostatic")
+	fs.BoolVar(&opts.HTTP, "http", false, "also generate an http.FileSystem and an http.Handler for each directory")
+	fs.BoolVar(&opts.Precompressed, "precompressed", false, "keep the gzip content of the files, returned by the generated GetCompressed functions and served as is by the handlers to the clients accepting it")
+	fs.BoolVar(&opts.Brotli, "brotli", false, "also store a Brotli variant of the compressible files, served to the clients accepting it, needs -http")
+	fs.StringVar(&opts.CacheControl, "cache-control", "no-cache", "default Cache-Control header sent by the generated handlers")
+	fs.BoolVar(&opts.Overrides, "overrides", false, "also generate a handler serving the files of a directory given at run time in place of the embedded ones, needs -http")
+	fs.BoolVar(&opts.IOFS, "iofs", false, "make the FS of each directory an io/fs.FS, with a test")
+	fs.BoolVar(&opts.SelfTest, "self-test", false, "also generate a test reading back every file, checking its size and hash")
+	fs.BoolVar(&opts.Bench, "bench", false, "also generate benchmarks of decompressing the files, of the Get functions and of the handlers")
+	fs.BoolVar(&opts.Manifest, "manifest", false, "also write manifest.txt, listing the name, size and SHA-256 of every file, for reviews")
+	fs.BoolVar(&opts.Merge, "merge", false, "put the files of all the directories behind a single set of functions")
+	names := fs.String("name", "assets", "name of the file and functions of the merged directories with -merge, or comma separated names of directories, like 'my-assets=LegacyAssets'")
+	maps := make(nameMap)
+	fs.Var(maps, "map", "name of the file and functions of a directory, like 'web/dist=Web', can be repeated")
+	fs.BoolVar(&opts.Lazy, "lazy", false, "decompress each file on first access instead of at init")
+	fs.BoolVar(&opts.Release, "release", false, "also generate functions dropping the decompressed content of the files, to free memory")
+	rawexts := fs.String("no-compress-ext", strings.Join(gen.DefaultNoCompressExt, ","), "comma separated extensions of files to store without compression")
+	fs.Var((*stringList)(&opts.Pipe), "pipe", "rule running the files matching a glob through a command, embedding its output, like '*.md=pandoc -t html', can be repeated")
+	fs.StringVar(&opts.Cache, "cache", "", "file keeping the compressed files between runs, to only compress the files that changed, like .gostatic-cache")
+	fs.BoolVar(&opts.Minify, "minify", false, "minify the HTML, CSS, JavaScript, SVG and JSON files before compressing them")
+	fs.BoolVar(&opts.Gunzip, "gunzip", false, "embed the files ending with .gz as the content they hold, without .gz, storing their gzip stream as is with the gzip codec")
+	fs.BoolVar(&opts.Dev, "dev", false, "also generate code reading the directories from disk, built with -tags dev")
+	maxFileSize := fs.String("max-file-size", "", "size a file mustn't exceed before compression, like 10MB")
+	maxTotalSize := fs.String("max-total-size", "", "size all the files mustn't exceed before compression, like 50MB")
+	memoryBudget := fs.String("memory-budget", "", "size of the decompressed content the generated package keeps in memory, releasing the least recently used files above it, like 64MB, implies -lazy and -release")
+	solid := fs.String("solid", "", "compress the files smaller than this size together, in blocks, like 4KB")
+	inlineThreshold := fs.String("inline-threshold", "", "with -backend pak, keep the files stored in fewer bytes than this in the Go source, like 256KB")
+	encrypt := fs.Bool("encrypt", false, "encrypt the files with AES-GCM, with the hex encoded key read from the -key-env variable, both now and at run time")
+	fs.StringVar(&opts.KeyEnv, "key-env", gen.DefaultKeyEnv, "environment variable holding the hex encoded key of -encrypt")
+	sign := fs.String("sign", "", "PEM file holding the ed25519 private key signing a manifest of the files, checked by the generated package at init")
+	fs.BoolVar(&opts.BudgetWarn, "budget-warn", false, "only warn when a file or all the files exceed their maximum size")
+	fs.StringVar(&opts.OnError, "on-error", "fail", "what to do with files that can't be read: fail without writing anything, or skip them")
+	fs.StringVar(&opts.Symlinks, "symlinks", "skip", "what to do with symlinks: skip them, follow them or error")
+	fs.StringVar(&opts.Normalize, "normalize", "none", "how to normalize the names of the files: none, nfc, writing them in Unicode NFC, or nfc-lookups, also normalizing the names looked up, which then needs golang.org/x/text")
+	follow := fs.Bool("follow-symlinks", false, "follow symlinks, like -symlinks follow")
+	fs.BoolVar(&opts.NoIgnore, "no-ignore", false, "don't skip the files listed in .gitignore and .gostaticignore")
+	fs.BoolVar(&opts.ModTime, "modtime", false, "record the modification times of the files, which then differ between checkouts of the same files")
+	fs.BoolVar(&opts.EmptyDirs, "empty-dirs", false, "keep the empty directories, listed when reading directories and created by extract")
+	fs.BoolVar(&opts.Integrity, "integrity", false, "compute the Subresource Integrity values of scripts and style sheets")
+	fs.StringVar(&opts.Extract, "extract", "none", "generate functions writing the files to disk with their modes: none, always writing them, or changed, leaving those already holding their content")
+	fs.BoolVar(&opts.Obfuscate, "obfuscate", false, "replace the names of the files with hashes in the generated code, leaving out the functions listing them")
+	fs.BoolVar(&opts.IgnoreCase, "ignore-case", false, "have the functions find the files whose names only differ by case from the ones given, like on macOS and Windows")
+	fs.BoolVar(&opts.Fingerprint, "fingerprint", false, "add the hash of the content of each file to its name, like app.3f9ab2c1.css")
+	fs.StringVar(&opts.Codec, "codec", "", "compression to use: gzip, the default, zlib, flate, zstd or none")
+	level := fs.String("level", "default", "compression level: 1-9, fastest, best or default")
+	fs.StringVar(&opts.Encoding, "encoding", "", "how to write the data in Go source: base64, the default, base256, string or bytes")
+	fs.IntVar(&opts.Jobs, "j", runtime.NumCPU(), "number of files to read and compress at once")
+	fs.IntVar(&opts.ChunkSize, "chunk-size", gen.DefaultChunkSize, "size in bytes above which a file is split in several literals, -1 to never split")
+	fs.IntVar(&opts.SplitSize, "split-size", gen.DefaultSplitSize, "size in bytes above which the data of a directory is split across files, -1 to never split")
+	includes := fs.String("include", "", "comma separated globs of the files to embed, like '**/*.html'")
+	excludes := fs.String("exclude", "", "comma separated globs of the files and directories to skip, like '**/*.map'")
+	templates := fs.String("templates", "", "comma separated globs of the files to parse with the generated Templates functions, like '*.tmpl'")
+	fs.BoolVar(&opts.HTMLTemplates, "html-templates", false, "parse the files given with -templates with html/template instead of text/template")
+	locales := fs.String("locales", "", "comma separated globs of the translation catalogs, named after their language tag, found with the generated Locale functions, like 'locales/*.json'")
+	fs.StringVar(&opts.DefaultLocale, "default-locale", "", "language tag of the catalog the generated Locale functions fall back on, like en")
+	trimPrefix := fs.String("trim-prefix", "", "prefix to remove from the names of the files, like 'web/dist'")
+	fs.StringVar(&opts.Backend, "backend", "literal", "how to store the files: literal, in Go literals, embed, with go:embed, bundle, packed in one embedded file per directory, or pak, in a .pak file per directory to ship next to the executable")
+	fs.StringVar(&opts.Tags, "tags", "", "build constraint to add to every file, like 'embed_assets' or 'full && !lite'")
+	fs.StringVar(&opts.Template, "template", "", "file holding a custom template for the file of each directory")
+	rewrites := fs.String("rewrite", "", "comma separated rules renaming the files, like 'old/=>new/'")
+	progress := fs.String("progress", "auto", "show a progress bar instead of a line per file: auto, for directories of many files on a terminal, always or never")
+	config := fs.String("config", "", "YAML or TOML file holding flags and directories, overridden by the flags given")
+	fs.Var((*stringList)(&opts.Remote), "remote", "URL of a zip or tar archive to download and embed, which can end with #sha256=HEX to pin its checksum, can be repeated")
+	fs.Var((*stringList)(&opts.Git), "git", "directory of a git repository to embed, written repo@ref:path, which can end with #commit=HEX to pin the commit of ref, can be repeated")
+	fileList := fs.String("files", "", "file listing files to embed along with the directories, one per line, like the output of git ls-files, or - for the standard input")
+	chdir := fs.String("C", "", "change to this directory first, which the paths given are relative to")
+	goGenerate := fs.Bool("go-generate", false, "write a go:generate directive running gostatic again with the same flags in the package")
+	relativePaths := fs.Bool("relative-paths", false, "make the absolute paths of the directories and of the go:generate directive relative to the working directory, for them to work in other checkouts")
+	var specs []string
+	fs.Var((*stringList)(&specs), "spec", "package to generate instead of a single one, written 'pkgdir=dirnames', comma separated, named after the last element of pkgdir, like 'internal/webfs=web/dist', can be repeated")
+
+	return func() []gen.Options {
+		if *chdir != "" {
+			if err := os.Chdir(*chdir); err != nil {
+				elog.Fatalf("Invalid -C: %v", err)
+			}
+		}
+		dirs := fs.Args()
+		if *config != "" {
+			configDirs, err := loadConfig(fs, *config)
+			if err != nil {
+				elog.Fatalf("Invalid -config: %v", err)
+			}
+			setupLogs()
+			if len(dirs) == 0 {
+				dirs = configDirs
+			}
+		}
 
-	file, err := os.Create(destfilename)
-	if err != nil {
-		return err
+		var err error
+		if opts.Level, err = parseLevel(*level); err != nil {
+			elog.Fatalf("Invalid -level: %v", err)
+		}
+		opts.NoCompressExt = splitList(*rawexts)
+		opts.Include = splitList(*includes)
+		opts.Exclude = splitList(*excludes)
+		opts.Templates = splitList(*templates)
+		opts.Locales = splitList(*locales)
+		opts.TrimPrefix = *trimPrefix
+		opts.Rewrite = splitList(*rewrites)
+		if opts.MaxFileSize, err = parseSize(*maxFileSize); err != nil {
+			elog.Fatalf("Invalid -max-file-size: %v", err)
+		}
+		if opts.MaxTotalSize, err = parseSize(*maxTotalSize); err != nil {
+			elog.Fatalf("Invalid -max-total-size: %v", err)
+		}
+		if opts.MemoryBudget, err = parseSize(*memoryBudget); err != nil {
+			elog.Fatalf("Invalid -memory-budget: %v", err)
+		}
+		if opts.Solid, err = parseSize(*solid); err != nil {
+			elog.Fatalf("Invalid -solid: %v", err)
+		}
+		if opts.InlineThreshold, err = parseSize(*inlineThreshold); err != nil {
+			elog.Fatalf("Invalid -inline-threshold: %v", err)
+		}
+		if *encrypt {
+			if opts.Key, err = hex.DecodeString(strings.TrimSpace(os.Getenv(opts.KeyEnv))); err != nil || len(opts.Key) == 0 {
+				elog.Fatalf("Need a hex encoded key in $%s to encrypt the files", opts.KeyEnv)
+			}
+		}
+		if *sign != "" {
+			if opts.SignKey, err = readSignKey(*sign); err != nil {
+				elog.Fatalf("Couldn't read the signing key of -sign: %v", err)
+			}
+		}
+		if *follow {
+			if opts.Symlinks != "skip" && opts.Symlinks != "follow" {
+				elog.Fatalf("Invalid -follow-symlinks along with -symlinks %s", opts.Symlinks)
+			}
+			opts.Symlinks = "follow"
+		}
+		if opts.Name, opts.Names, err = parseNames(*names); err != nil {
+			elog.Fatalf("Invalid -name: %v", err)
+		}
+		for dirname, name := range maps {
+			if other, ok := opts.Names[dirname]; ok && other != name {
+				elog.Fatalf("Invalid -map: %q is named both %s and %s", dirname, other, name)
+			}
+			opts.Names[dirname] = name
+		}
+		if opts.Append {
+			// the package has its name already
+			pkgname := false
+			fs.Visit(func(f *flag.Flag) { pkgname = pkgname || f.Name == "pkgname" })
+			if !pkgname {
+				opts.PkgName = ""
+			}
+			if *goGenerate {
+				elog.Fatalf("Invalid -go-generate along with -append, the package has its own go:generate directives")
+			}
+		}
+		opts.Command = joinWords(commandLine(fs, os.Args[1:], true), shellQuote)
+		opts.Log = infoLog()
+		opts.Verbose = verbose
+		switch *progress {
+		case "auto":
+			// the lines of the files are wanted with -v
+			if !quiet && !verbose && isTerminal(os.Stdout) {
+				opts.Progress, opts.ProgressMin = os.Stdout, progressFiles
+			}
+		case "always":
+			if !quiet {
+				opts.Progress = os.Stdout
+			}
+		case "never":
+		default:
+			elog.Fatalf("Invalid -progress %q, want auto, always or never", *progress)
+		}
+		opts.ErrorLog = elog
+
+		sources := func(opts *gen.Options, names []string) {
+			for _, name := range names {
+				if *relativePaths {
+					// the files are named after the paths
+					name = relativePath(name)
+				}
+				// the files given on their own go together, archives are
+				// read like directories
+				if fi, err := os.Stat(name); err == nil && !fi.IsDir() && !gen.IsArchive(name) {
+					opts.Files = append(opts.Files, name)
+				} else {
+					opts.Dirs = append(opts.Dirs, name)
+				}
+			}
+		}
+		if len(specs) != 0 {
+			return specPackages(fs, opts, specs, dirs, *fileList != "", *goGenerate, *relativePaths, sources)
+		}
+
+		if *fileList != "" {
+			if opts.Files, err = readFileList(*fileList); err != nil {
+				elog.Fatalf("Couldn't read the list of -files: %v", err)
+			}
+		}
+		sources(&opts, dirs)
+		if len(opts.Dirs) < 1 && len(opts.Files) < 1 && len(opts.Remote) < 1 && len(opts.Git) < 1 {
+			elog.Fatalf(`Need to specify at least one directory or file.
+usage: %s %s [flags] [dirnames or filenames]`, os.Args[0], fs.Name())
+		}
+		if *goGenerate && opts.Out == "" {
+			opts.Generate = generateCommand(fs, os.Args[1:], opts, *relativePaths)
+		}
+		return []gen.Options{opts}
 	}
+}
 
-	err = filetempl.Execute(file, struct {
-		PkgName  string
-		RootName string
-		RootMap  map[string]string
-	}{
-		PkgName:  pkgname,
-		RootName: destfunction,
-		RootMap:  fakefs,
+// specPackages returns the options of the packages of the -spec flags, which
+// only differ from opts by their directory, name and sources. The sources
+// must all be given with -spec. The go:generate directive regenerating them
+// all is written in the first one.
+func specPackages(fs *flag.FlagSet, opts gen.Options, specs, dirs []string, fileList, goGenerate, relative bool, sources func(*gen.Options, []string)) []gen.Options {
+	if len(dirs) != 0 || fileList || len(opts.Remote) != 0 || len(opts.Git) != 0 {
+		elog.Fatalf("Invalid -spec: the directories and files of the packages must be given with -spec")
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "o", "out", "pkgname":
+			elog.Fatalf("Invalid -%s along with -spec, which names the packages", f.Name)
+		}
 	})
-	if err != nil {
-		_ = file.Close()
-		return err
+	pkgs := make([]gen.Options, 0, len(specs))
+	for _, spec := range specs {
+		i := strings.Index(spec, "=")
+		if i <= 0 || len(splitList(spec[i+1:])) == 0 {
+			elog.Fatalf("Invalid -spec %q, want pkgdir=dirnames", spec)
+		}
+		pkg := opts
+		pkg.Output = filepath.Clean(spec[:i])
+		if !opts.Append {
+			pkg.PkgName = filepath.Base(pkg.Output)
+		}
+		sources(&pkg, splitList(spec[i+1:]))
+		if goGenerate && len(pkgs) == 0 {
+			pkg.Generate = generateCommand(fs, os.Args[1:], pkg, relative)
+		}
+		pkgs = append(pkgs, pkg)
 	}
+	return pkgs
+}
 
-	return file.Close()
+// parseLevel reads a compression level, either a number from 1 to 9 or one
+// of `fastest`, `best` and `default`.
+func parseLevel(level string) (int, error) {
+	switch strings.ToLower(level) {
+	case "fastest":
+		return 1, nil
+	case "best":
+		return 9, nil
+	case "default", "":
+		return 0, nil
+	}
+	n, err := strconv.Atoi(level)
+	if err != nil || n < 1 || n > 9 {
+		return 0, fmt.Errorf("invalid level %q, want 1-9, fastest, best or default", level)
+	}
+	return n, nil
 }
 
-type logtabwriter struct {
-	tab *tabwriter.Writer
+// parseSize reads a size in bytes, like 1048576, 1MiB or 1MB. An empty size
+// is 0, for no limit.
+func parseSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	n, err := humanize.ParseBytes(size)
+	if err != nil {
+		return 0, err
+	}
+	return int64(n), nil
 }
 
-func newLogtab(w io.Writer) io.Writer {
-	return &logtabwriter{tabwriter.NewWriter(w, 2, 2, 1, '\t', 0)}
+// runFlags are the flags that don't change the package generated, which
+// commandLine leaves out.
+var runFlags = map[string]bool{
+	"check": true, "watch": true, "dry-run": true, "report": true, "report-out": true, "stats": true,
+	"q": true, "v": true, "no-color": true, "j": true, "progress": true, "C": true,
 }
 
-// compile check
-var _ io.Writer = &logtabwriter{}
+// commandLine returns the words of the command line args of fs, running
+// gostatic, without the runFlags. The absolute
+// paths are made relative to the working directory if relative is set, so
+// that it doesn't depend on where the project is.
+func commandLine(fs *flag.FlagSet, args []string, relative bool) []string {
+	rel := func(name string) string {
+		if !relative {
+			return name
+		}
+		return relativePath(name)
+	}
+	words := []string{"gostatic"}
+	if len(args) > 0 && args[0] == fs.Name() {
+		words, args = append(words, args[0]), args[1:]
+	}
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			// the flags end there
+			for _, arg := range append([]string{arg}, args...) {
+				words = append(words, rel(arg))
+			}
+			break
+		}
+		name, value := strings.TrimLeft(arg, "-"), ""
+		hasValue := false
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value, hasValue = name[:i], name[i+1:], true
+		}
+		if f := fs.Lookup(name); f != nil && !hasValue {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				if len(args) > 0 {
+					value, hasValue, args = args[0], true, args[1:]
+				}
+			}
+		}
+		if runFlags[name] {
+			continue
+		}
+		if name == "spec" {
+			// the package and its sources are paths too
+			var names []string
+			if i := strings.Index(value, "="); i >= 0 {
+				for _, name := range splitList(value[i+1:]) {
+					names = append(names, rel(name))
+				}
+				value = rel(value[:i]) + "=" + strings.Join(names, ",")
+			}
+			words = append(words, "-spec="+value)
+		} else if hasValue {
+			words = append(words, "-"+name+"="+rel(value))
+		} else {
+			words = append(words, "-"+name)
+		}
+	}
+	return words
+}
 
-func (l *logtabwriter) Write(p []byte) (int, error) {
-	n, err := l.tab.Write(p)
+// relativePath returns name relative to the working directory if it is
+// absolute.
+func relativePath(name string) string {
+	if !filepath.IsAbs(name) {
+		return name
+	}
+	wd, err := os.Getwd()
 	if err != nil {
-		return n, err
+		return name
 	}
-	return n, l.tab.Flush()
+	if rel, err := filepath.Rel(wd, name); err == nil {
+		return rel
+	}
+	return name
 }
 
-func snakify(input string) string {
-	out := bytes.NewBuffer(nil)
-	lastWasSnake := true
+// generateCommand returns the command line args of fs, to be run by go
+// generate in the directory of the package of opts. It changes back to the
+// working directory first, so that the names of the files don't change.
+func generateCommand(fs *flag.FlagSet, args []string, opts gen.Options, relative bool) string {
+	words := commandLine(fs, args, relative)
+	pkgdir := opts.Output
+	if pkgdir == "" {
+		pkgdir = opts.PkgName
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		elog.Fatalf("Couldn't write the go:generate directive: %v", err)
+	}
+	dir := wd
+	if abs, err := filepath.Abs(pkgdir); err == nil {
+		if rel, err := filepath.Rel(abs, wd); err == nil {
+			dir = rel
+		}
+	}
+	// the subcommand comes before the flags
+	n := 1
+	if len(words) > 1 && words[1] == fs.Name() {
+		n = 2
+	}
+	head := append(words[:n:n], "-C="+filepath.ToSlash(dir))
+	return joinWords(append(head, words[n:]...), generateQuote)
+}
+
+// joinWords joins the words of a command line, quoted with quote.
+func joinWords(words []string, quote func(string) string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = quote(word)
+	}
+	return strings.Join(quoted, " ")
+}
 
-	for i, r := range []rune(input) {
+// generateQuote quotes s for a go:generate directive, unless it doesn't need
+// it.
+func generateQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'`\\") {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// shellQuote quotes s for a POSIX shell, unless it doesn't need it.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
 
-		switch {
-		case unicode.IsLetter(r):
-			_, _ = out.WriteRune(r)
-			lastWasSnake = false
-		case lastWasSnake:
-			// skip it
-		case i != len(input)-1:
-			_, _ = out.WriteRune('_')
+// readFileList reads the names of files listed one per line in the file
+// called filename, or in the standard input if it is -. Blank lines are
+// skipped.
+func readFileList(filename string) ([]string, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if name := strings.TrimRight(line, "\r"); strings.TrimSpace(name) != "" {
+			names = append(names, name)
 		}
 	}
-	return out.String()
+	return names, nil
 }
 
-func camelize(input string) string {
-	out := bytes.NewBuffer(nil)
-	needCamel := true
-	for _, r := range []rune(input) {
+// readSignKey reads an ed25519 private key from a PEM file, as written by
+// openssl genpkey -algorithm ed25519.
+func readSignKey(filename string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", filename)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edkey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s doesn't hold an ed25519 key", filename)
+	}
+	return edkey, nil
+}
 
-		switch {
-		case unicode.IsLetter(r) && needCamel:
-			_, _ = out.WriteRune(unicode.ToUpper(r))
-			needCamel = false
-		case unicode.IsLetter(r) && !needCamel:
-			_, _ = out.WriteRune(r)
-		default:
-			needCamel = true
+// parseNames reads the name of the merged directories, and the names of
+// directories written `dirname=name`, in a comma separated list.
+func parseNames(list string) (string, map[string]string, error) {
+	name := "assets"
+	names := make(nameMap)
+	for _, elem := range splitList(list) {
+		if !strings.Contains(elem, "=") {
+			name = elem
+			continue
+		}
+		if err := names.Set(elem); err != nil {
+			return "", nil, err
 		}
 	}
-	return out.String()
+	return name, names, nil
 }
 
-var filetempl = template.Must(template.New("file").Parse(`// GENERATED FILE: Do not edit, all changes will be lost.
+// nameMap holds the names of directories, given with a repeated flag.
+type nameMap map[string]string
 
-// Package {{.PkgName}} contains the content of files from {{.RootName}}. It
-// is generated by:
-//     https://github.com/aybabtme/gostatic
-package {{.PkgName}}
+// compile check
+var _ flag.Value = nameMap{}
 
-import (
-    "bytes"
-    "compress/gzip"
-    "encoding/base64"
-    "io/ioutil"
-    "log"
-)
+func (m nameMap) String() string {
+	elems := make([]string, 0, len(m))
+	for dirname, name := range m {
+		elems = append(elems, dirname+"="+name)
+	}
+	sort.Strings(elems)
+	return strings.Join(elems, ",")
+}
+
+// Set adds a name written `dirname=name`.
+func (m nameMap) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 0 {
+		return fmt.Errorf("%q isn't written dirname=name", value)
+	}
+	dirname, name := strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:])
+	if dirname == "" || name == "" {
+		return fmt.Errorf("%q isn't written dirname=name", value)
+	}
+	if other, ok := m[dirname]; ok && other != name {
+		return fmt.Errorf("%q is named both %s and %s", dirname, other, name)
+	}
+	m[dirname] = name
+	return nil
+}
+
+// stringList holds the values of a repeated flag.
+type stringList []string
+
+// compile check
+var _ flag.Value = (*stringList)(nil)
 
-// Get{{.RootName}} will lookup the static assets. It returns a *bytes.Reader
-// and true if found, false otherwise. The static assets contain exactly the
-// following entries:
-// {{range $name, $data := .RootMap}}
-//   {{$name}}{{end}}
-//
-func Get{{.RootName}}(filename string) (*bytes.Reader, bool) {
-    data, ok := decompressed{{.RootName}}[filename]
-    return bytes.NewReader(data), ok
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ", ")
+}
+
+// Set adds a value.
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
-// List{{.RootName}} will return all the static assets sharing root
-// {{.RootName}}.
-func List{{.RootName}}() (map[string]*bytes.Reader) {
-	out := make(map[string]*bytes.Reader, len(decompressed{{.RootName}}))
-	for k, v := range decompressed{{.RootName}} {
-		out[k] = bytes.NewReader(v)
+// splitList splits a comma separated list, dropping empty elements.
+func splitList(list string) []string {
+	elems := []string{}
+	for _, elem := range strings.Split(list, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			elems = append(elems, elem)
+		}
 	}
-	return out
+	return elems
 }
 
-var decompressed{{.RootName}} = make(map[string][]byte)
+type logtabwriter struct {
+	tab *tabwriter.Writer
+}
 
-func init() {
+func newLogtab(w io.Writer) io.Writer {
+	return &logtabwriter{tabwriter.NewWriter(w, 2, 2, 1, '\t', 0)}
+}
 
-	var compressed = [...]struct {
-        name   string
-        gzip64 string
-    }{ {{range $name, $data := .RootMap}}
-        {"{{$name}}", ` + "`{{$data}}`" + `},{{end}}
-    }
+// compile check
+var _ io.Writer = &logtabwriter{}
 
-	for _, file := range compressed {
-		gzipdata, err := base64.StdEncoding.DecodeString(file.gzip64)
-		if err != nil {
-            log.Panicf("Couldn't decode base64 data for %q: %v", file.name, err)
-        }
-		gr, err := gzip.NewReader(bytes.NewBuffer(gzipdata))
-        if err != nil {
-            log.Panicf("Couldn't open gzip stream for data for %q: %v", file.name, err)
-        }
-        data, err := ioutil.ReadAll(gr)
-        if err != nil {
-            log.Panicf("Couldn't decompress gzip data in %q: %v", file.name, err)
-        }
-        decompressed{{.RootName}}[file.name] = data
-    }
-}
-`))
+func (l *logtabwriter) Write(p []byte) (int, error) {
+	n, err := l.tab.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, l.tab.Flush()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aybabtme/gostatic/gen"
+	"github.com/fsnotify/fsnotify"
+)
+
+// debounce is how long the files must stay untouched before the package is
+// regenerated, so that a burst of changes causes a single regeneration.
+const debounce = 250 * time.Millisecond
+
+// watch regenerates the package each time files change in the directories,
+// or next to the files given on their own.
+// It returns when ctx is done, or if watching fails.
+func watch(ctx context.Context, opts gen.Options) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = w.Close() }()
+
+	output := opts.Output
+	if opts.Out != "" {
+		output = opts.Out
+	} else if output == "" {
+		output = opts.PkgName
+	}
+	pkgdir, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+
+	// the directories holding the files and archives are watched, as editors
+	// often replace files rather than write them
+	holders := append([]string(nil), opts.Files...)
+	for _, dirname := range opts.Dirs {
+		if fi, err := os.Stat(dirname); err == nil && !fi.IsDir() {
+			holders = append(holders, dirname)
+			continue
+		}
+		if err := watchTree(w, dirname, pkgdir); err != nil {
+			return err
+		}
+	}
+	watched := make(map[string]bool)
+	for _, filename := range holders {
+		dirname := filepath.Dir(filename)
+		if watched[dirname] {
+			continue
+		}
+		watched[dirname] = true
+		if err := w.Add(dirname); err != nil {
+			return err
+		}
+	}
+	if len(opts.Files) != 0 {
+		log.Printf("Watching %d directories and %d files for changes", len(opts.Dirs), len(opts.Files))
+	} else {
+		log.Printf("Watching %d directories for changes", len(opts.Dirs))
+	}
+
+	var regenerate <-chan time.Time
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if abs, err := filepath.Abs(ev.Name); err == nil && isWithin(abs, pkgdir) {
+				continue
+			}
+			if ev.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					if err := watchTree(w, ev.Name, pkgdir); err != nil {
+						elog.Printf("Couldn't watch %q: %v", ev.Name, err)
+					}
+				}
+			}
+			regenerate = time.After(debounce)
+
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			elog.Printf("Error while watching: %v", err)
+
+		case <-ctx.Done():
+			return nil
+
+		case <-regenerate:
+			regenerate = nil
+			log.Printf("Files changed, regenerating package %q", opts.PkgName)
+			if err := gen.Generate(ctx, opts); err != nil {
+				elog.Print(err)
+			}
+		}
+	}
+}
+
+// watchTree watches dirname and all its subdirectories, except for the
+// package directory itself.
+func watchTree(w *fsnotify.Watcher, dirname, pkgdir string) error {
+	return filepath.Walk(dirname, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+		if abs, err := filepath.Abs(name); err == nil && isWithin(abs, pkgdir) {
+			return filepath.SkipDir
+		}
+		if fi.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		return w.Add(name)
+	})
+}
+
+// isWithin tells if the absolute path name is dir or is inside of it.
+func isWithin(name, dir string) bool {
+	rel, err := filepath.Rel(dir, name)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}